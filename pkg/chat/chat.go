@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 )
@@ -34,3 +35,8 @@ type Chat interface {
 	Start(ctx context.Context, scanner *bufio.Scanner) error
 	Close(ctx context.Context) error
 }
+
+// Indexer manages the local index of content retrieved for prompts.
+type Indexer interface {
+	Index(ctx context.Context, args []string, w io.Writer) error
+}
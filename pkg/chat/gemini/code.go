@@ -0,0 +1,131 @@
+package gemini
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mchmarny/aictl/pkg/markdown"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	codeCommand = "/code"
+	codeUsage   = "usage: /code [save N path | copy N]"
+)
+
+func defaultCopyCommand() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "pbcopy"
+	case "windows":
+		return "clip"
+	default:
+		return "xclip -selection clipboard"
+	}
+}
+
+// code lists the code blocks in the last response, or saves or copies
+// the selected one.
+func (c *Chat) code(arg string, scanner *bufio.Scanner) error {
+	blocks := markdown.CodeBlocks(c.last)
+	if len(blocks) == 0 {
+		return errors.New("no code blocks in the last response")
+	}
+
+	args := strings.Fields(arg)
+	if len(args) == 0 {
+		for i, b := range blocks {
+			lines := strings.Split(strings.TrimRight(b.Code, "\n"), "\n")
+			lang := b.Lang
+			if lang == "" {
+				lang = "text"
+			}
+			aiStyle.Printf("%d. %s (%d lines): %s\n", i+1, lang, len(lines), strings.TrimSpace(lines[0]))
+		}
+		return nil
+	}
+
+	if len(args) < 2 {
+		return errors.New(codeUsage)
+	}
+
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 || n > len(blocks) {
+		return errors.Errorf("invalid code block, expected number between 1 and %d", len(blocks))
+	}
+	b := blocks[n-1]
+
+	switch args[0] {
+	case "save":
+		if len(args) != 3 {
+			return errors.New(codeUsage)
+		}
+		return saveCode(b, args[2], scanner)
+	case "copy":
+		return copyCode(b, c.copyCommand)
+	default:
+		return errors.New(codeUsage)
+	}
+}
+
+// saveCode writes the code block to file at path. When the file already
+// exists, the diff is printed and user is asked to confirm the overwrite.
+func saveCode(b *markdown.CodeBlock, path string, scanner *bufio.Scanner) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error reading file: %s", path)
+	}
+
+	if err == nil {
+		if string(existing) == b.Code {
+			aiStyle.Printf("%s is already up to date.\n", path)
+			return nil
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(existing)),
+			B:        difflib.SplitLines(b.Code),
+			FromFile: path,
+			ToFile:   path,
+			Context:  3,
+		})
+		if err != nil {
+			return errors.Wrap(err, "error creating diff")
+		}
+
+		aiStyle.Println(diff)
+		if !confirm(fmt.Sprintf("Overwrite %s?", path), scanner) {
+			return nil
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(b.Code), 0o644); err != nil {
+		return errors.Wrapf(err, "error writing file: %s", path)
+	}
+
+	aiStyle.Printf("Saved to %s.\n", path)
+	return nil
+}
+
+// copyCode pipes the code block into the copy command.
+func copyCode(b *markdown.CodeBlock, command string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return errors.New("copy command not set")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(b.Code)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "error running %s: %s", args[0], strings.TrimSpace(string(out)))
+	}
+
+	aiStyle.Println("Copied.")
+	return nil
+}
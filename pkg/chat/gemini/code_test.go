@@ -0,0 +1,64 @@
+package gemini
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode(t *testing.T) {
+	c := Chat{}
+	none := bufio.NewScanner(strings.NewReader(""))
+
+	t.Run("Without code", func(t *testing.T) {
+		assert.Error(t, c.code("", none))
+	})
+
+	c.last = "Example:\n```go\npackage main\n```\n"
+	path := filepath.Join(t.TempDir(), "main.go")
+
+	t.Run("List", func(t *testing.T) {
+		assert.NoError(t, c.code("", none))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Error(t, c.code("save", none))
+		assert.Error(t, c.code("save 2 "+path, none))
+		assert.Error(t, c.code("move 1", none))
+	})
+
+	t.Run("Save new", func(t *testing.T) {
+		assert.NoError(t, c.code("save 1 "+path, none))
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "package main\n", string(b))
+	})
+
+	t.Run("Save existing", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("package other\n"), 0o600))
+
+		assert.NoError(t, c.code("save 1 "+path, bufio.NewScanner(strings.NewReader("n\n"))))
+		b, _ := os.ReadFile(path)
+		assert.Equal(t, "package other\n", string(b))
+
+		assert.NoError(t, c.code("save 1 "+path, bufio.NewScanner(strings.NewReader("y\n"))))
+		b, _ = os.ReadFile(path)
+		assert.Equal(t, "package main\n", string(b))
+
+		assert.NoError(t, os.WriteFile(path, []byte("package other\n"), 0o600))
+		assert.NoError(t, c.code("save 1 "+path, bufio.NewScanner(strings.NewReader("yes\n"))))
+		b, _ = os.ReadFile(path)
+		assert.Equal(t, "package main\n", string(b))
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		c.copyCommand = "cat"
+		assert.NoError(t, c.code("copy 1", none))
+		c.copyCommand = "command-not-exists"
+		assert.Error(t, c.code("copy 1", none))
+	})
+}
@@ -0,0 +1,419 @@
+package gemini
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/content/file"
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/mchmarny/aictl/pkg/project"
+	"github.com/mchmarny/aictl/pkg/redact"
+	"github.com/pkg/errors"
+)
+
+const (
+	contextCommand = "/context"
+	contextUsage   = "usage: /context list, /context show ID, /context drop ID, or /context refresh ID"
+
+	// approximate number of characters per token
+	tokenChars = 4
+
+	projectInstructions = "Project instructions, follow them in all your answers:"
+)
+
+// contextItem is content loaded into the chat. Text content is sent
+// before the chat history along with each prompt, content with images is
+// attached to the next prompt only, as the images can't be part of the
+// chat history.
+type contextItem struct {
+	id          int
+	source      string // prompt which loaded the content (e.g. FILE:path), without credentials
+	description string
+	loaded      time.Time
+	parts       []content.Part
+	tables      []*table.Table
+	// pinned content is loaded from the project, it can't be dropped
+	pinned bool
+
+	loader   content.Loader
+	location string
+}
+
+// attached returns true when the item includes images.
+func (i *contextItem) attached() bool {
+	_, err := content.JoinText(i.parts)
+	return err != nil
+}
+
+// tokens returns estimated number of tokens of the text parts.
+func (i *contextItem) tokens() int {
+	n := 0
+	for _, p := range i.parts {
+		if t, ok := p.(content.Text); ok {
+			n += len(t)
+		}
+	}
+	return (n + tokenChars - 1) / tokenChars
+}
+
+// readContent loads content at location using the loader and adds it to
+// the chat context.
+func (c *Chat) readContent(ctx context.Context, l content.Loader, location string, scanner *bufio.Scanner) error {
+	item := &contextItem{
+		source:   contextSource(l, location),
+		loader:   l,
+		location: location,
+	}
+	ok, err := c.loadItem(ctx, item, scanner, false)
+	if err != nil || !ok {
+		return err
+	}
+	c.addContext(item)
+
+	if item.attached() {
+		aiStyle.Println("Image attached, it will be sent along with your next prompt:")
+		return nil
+	}
+	aiStyle.Printf("%s:\n", modelContentResponse)
+	return nil
+}
+
+// contextSource returns the prompt which loads location using the loader,
+// with the credentials in URL removed.
+func contextSource(l content.Loader, location string) string {
+	return l.Scheme() + ":" + url.Redact(location)
+}
+
+func (c *Chat) addContext(item *contextItem) {
+	c.nextID++
+	item.id = c.nextID
+	c.context = append(c.context, item)
+	c.addTables(item.tables)
+}
+
+// loadItem opens and loads the item source, asking for its description
+// when it's not set yet, and for confirmation when the source requires
+// it. Returns false when the source wasn't confirmed. When quiet, what is
+// loaded (e.g. the selected files) isn't printed.
+func (c *Chat) loadItem(ctx context.Context, item *contextItem, scanner *bufio.Scanner, quiet bool) (bool, error) {
+	src, err := item.loader.Open(ctx, item.location)
+	if err != nil {
+		return false, err
+	}
+	if !quiet {
+		for _, line := range src.Info() {
+			aiStyle.Println(line)
+		}
+	}
+	if cf, ok := src.(content.Confirmer); ok {
+		if q := cf.Confirm(); q != "" && !confirm(q, scanner) {
+			return false, nil
+		}
+	}
+
+	if item.description == "" {
+		if n := src.Size(); n > 0 {
+			aiStyle.Printf("Describe content of %s (~%d tokens):\n", src.Name(), (n+tokenChars-1)/tokenChars)
+		} else {
+			aiStyle.Printf("Describe content of %s:\n", src.Name())
+		}
+		scanner.Scan()
+		item.description = scanner.Text()
+	}
+
+	ct, err := src.Load(ctx, item.description)
+	if err != nil {
+		return false, err
+	}
+	if !quiet {
+		for _, line := range ct.Info {
+			aiStyle.Println(line)
+		}
+	}
+
+	item.parts = c.redactParts(item.source, ct.Parts)
+	item.tables = ct.Tables
+	item.loaded = time.Now()
+	return true, nil
+}
+
+// redactParts returns the parts with secrets redacted, and prints the
+// summary of what was redacted from source.
+func (c *Chat) redactParts(source string, parts []content.Part) []content.Part {
+	if c.redactor == nil {
+		return parts
+	}
+	parts, s := c.redactor.Parts(parts)
+	printRedacted(source, s)
+	return parts
+}
+
+// redactText returns txt with secrets redacted, and prints the summary of
+// what was redacted from source.
+func (c *Chat) redactText(source, txt string) string {
+	if c.redactor == nil {
+		return txt
+	}
+	txt, s := c.redactor.Text(txt)
+	printRedacted(source, s)
+	return txt
+}
+
+// restore returns txt with the redaction placeholders replaced by the
+// original values, e.g. in query written by the model.
+func (c *Chat) restore(txt string) string {
+	if c.redactor == nil {
+		return txt
+	}
+	return c.redactor.Restore(txt)
+}
+
+func printRedacted(source string, s redact.Summary) {
+	if len(s) > 0 {
+		aiStyle.Printf("Redacted %s from %s.\n", s, url.Redact(source))
+	}
+}
+
+// confirm asks the question and returns true when confirmed.
+func confirm(question string, scanner *bufio.Scanner) bool {
+	aiStyle.Printf("%s [y/N]:\n", question)
+	scanner.Scan()
+	if a := strings.ToLower(strings.TrimSpace(scanner.Text())); a != "y" && a != "yes" {
+		aiStyle.Println("Skipped.")
+		return false
+	}
+	return true
+}
+
+// contextHistory returns the text content loaded into the chat, each item
+// as user message followed by the model acknowledgement.
+func (c *Chat) contextHistory() []*genai.Content {
+	var h []*genai.Content
+	for _, item := range c.context {
+		if item.attached() {
+			continue
+		}
+		msg, _ := content.JoinText(item.parts)
+		h = append(h, textContent(userRole, msg), textContent(modelRole, modelContentResponse))
+	}
+	return h
+}
+
+// contextParts returns the text content loaded into the chat as parts of
+// single message, for the vision model which doesn't support chat history.
+func (c *Chat) contextParts() []genai.Part {
+	var parts []genai.Part
+	for _, item := range c.context {
+		if item.attached() {
+			continue
+		}
+		msg, _ := content.JoinText(item.parts)
+		parts = append(parts, genai.Text(msg))
+	}
+	return parts
+}
+
+// attachments returns the content with images, attached to the next
+// prompt.
+func (c *Chat) attachments() []content.Part {
+	var parts []content.Part
+	for _, item := range c.context {
+		if item.attached() {
+			parts = append(parts, item.parts...)
+		}
+	}
+	return parts
+}
+
+// sent removes the attached content once it was sent along with prompt,
+// it's then part of the chat history.
+func (c *Chat) sent() {
+	kept := c.context[:0]
+	for _, item := range c.context {
+		if !item.attached() {
+			kept = append(kept, item)
+		}
+	}
+	c.context = kept
+}
+
+// contextCmd lists, shows, drops, or refreshes the content loaded into
+// the chat.
+func (c *Chat) contextCmd(ctx context.Context, arg string, scanner *bufio.Scanner) error {
+	args := strings.Fields(arg)
+	if len(args) == 0 || args[0] == "list" {
+		return c.listContext()
+	}
+	if len(args) != 2 {
+		return errors.New(contextUsage)
+	}
+
+	i, err := c.findContext(args[1])
+	if err != nil {
+		return err
+	}
+	item := c.context[i]
+
+	switch args[0] {
+	case "show":
+		aiStyle.Printf("%s (%s)\n", item.source, item.description)
+		for _, p := range item.parts {
+			switch v := p.(type) {
+			case content.Text:
+				aiStyle.Println(string(v))
+			case *content.Blob:
+				aiStyle.Printf("[%s %s, %d bytes]\n", v.MIMEType, v.Name, len(v.Data))
+			}
+		}
+		return nil
+	case "drop":
+		if item.pinned {
+			return errors.Errorf("project context can't be dropped: %d %s, start with --%s to skip it", item.id, item.source, noProjFlag)
+		}
+		c.context = append(c.context[:i], c.context[i+1:]...)
+		c.dropTables(item.tables)
+		aiStyle.Printf("Dropped %d %s.\n", item.id, item.source)
+		return nil
+	case "refresh":
+		old := item.tables
+		ok, err := c.loadItem(ctx, item, scanner, false)
+		if err != nil || !ok {
+			return err
+		}
+		c.dropTables(old)
+		c.addTables(item.tables)
+		aiStyle.Printf("Refreshed %d %s.\n", item.id, item.source)
+		return nil
+	}
+	return errors.New(contextUsage)
+}
+
+func (c *Chat) listContext() error {
+	if len(c.context) == 0 {
+		aiStyle.Println("No content loaded.")
+		return nil
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSOURCE\tDESCRIPTION\tTOKENS\tLOADED")
+	for _, item := range c.context {
+		tokens := fmt.Sprintf("~%d", item.tokens())
+		if item.attached() {
+			tokens += " + images"
+		}
+		source := item.source
+		if item.pinned {
+			source += " (pinned)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", item.id, source, item.description, tokens, item.loaded.Format(time.TimeOnly))
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "error listing context")
+	}
+	aiStyle.Print(sb.String())
+	return nil
+}
+
+func (c *Chat) findContext(id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, errors.Errorf("invalid context ID: %s", id)
+	}
+	for i, item := range c.context {
+		if item.id == n {
+			return i, nil
+		}
+	}
+	return 0, errors.Errorf("context not found: %d", n)
+}
+
+// loadProject loads the instructions and context of the project as
+// pinned content. The project URLs are loaded only once confirmed, as
+// they are requested with the user credentials, so they are skipped when
+// the input is piped. Content which can't be loaded is skipped.
+func (c *Chat) loadProject(ctx context.Context, p *project.Project, scanner *bufio.Scanner) {
+	prompts := p.Context
+	if p.Instructions != "" {
+		prompts = append([]string{file.Scheme + ":" + p.Instructions}, prompts...)
+	}
+	if len(p.URLs) > 0 {
+		if interactive() {
+			aiStyle.Printf("Project %s loads %d URLs:\n  %s\n", p.Root, len(p.URLs), strings.Join(redactURLs(p.URLs), "\n  "))
+			if confirm("Load the project URLs?", scanner) {
+				prompts = append(prompts, p.URLs...)
+			}
+		} else {
+			// the answer would be read from the piped prompts
+			errStyle.Fprintf(errOut, "Skipped %d project URLs, they are loaded only when the input is a terminal.\n", len(p.URLs))
+		}
+	}
+
+	n := 0
+	for i, prompt := range prompts {
+		l, location, ok := c.loaders.Find(prompt)
+		if !ok {
+			errStyle.Fprintf(errOut, "Unsupported project context: %s\n", prompt)
+			continue
+		}
+		item := &contextItem{
+			source:      contextSource(l, location),
+			description: "Project context " + url.Redact(location),
+			loader:      l,
+			location:    location,
+			pinned:      true,
+		}
+		if i == 0 && p.Instructions != "" {
+			item.description = projectInstructions
+		}
+		ok, err := c.loadItem(ctx, item, scanner, true)
+		if err != nil {
+			errStyle.Fprintf(errOut, "Error loading project context: %s\n", err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		c.addContext(item)
+		n++
+	}
+
+	if n > 0 && interactive() {
+		aiStyle.Printf("Loaded %d project context items from %s, use '%s' to list them.\n", n, filepath.Join(p.Root, project.Dir), contextCommand)
+	}
+}
+
+func redactURLs(prompts []string) []string {
+	list := make([]string, 0, len(prompts))
+	for _, p := range prompts {
+		list = append(list, url.Redact(strings.TrimPrefix(p, url.Scheme+":")))
+	}
+	return list
+}
+
+// dropTables removes the tables from the tables available to /query.
+func (c *Chat) dropTables(tables []*table.Table) {
+	kept := c.tables[:0]
+	for _, t := range c.tables {
+		dropped := false
+		for _, d := range tables {
+			if t == d {
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			kept = append(kept, t)
+		}
+	}
+	c.tables = kept
+}
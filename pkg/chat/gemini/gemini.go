@@ -4,21 +4,36 @@ import (
 	"bufio"
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/google/generative-ai-go/genai"
+	"github.com/mchmarny/aictl/pkg/config"
+	"github.com/mchmarny/aictl/pkg/content"
 	"github.com/mchmarny/aictl/pkg/content/file"
+	"github.com/mchmarny/aictl/pkg/content/loaders"
+	"github.com/mchmarny/aictl/pkg/content/shell"
+	"github.com/mchmarny/aictl/pkg/content/structured"
+	"github.com/mchmarny/aictl/pkg/content/table"
 	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/mchmarny/aictl/pkg/format"
+	"github.com/mchmarny/aictl/pkg/index"
+	"github.com/mchmarny/aictl/pkg/markdown"
+	"github.com/mchmarny/aictl/pkg/project"
+	"github.com/mchmarny/aictl/pkg/redact"
 	"github.com/pkg/errors"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
 const (
-	modelType = "gemini-pro"
+	modelType       = "gemini-pro"
+	visionModelType = "gemini-pro-vision"
 
 	apiKeyEnvVar = "API_KEY"
 
@@ -27,32 +42,133 @@ const (
 	maxTokenFlag = "tokens"
 	topKFlag     = "top-k"
 	topPFlag     = "top-p"
+	candFlag     = "candidates"
+	stopFlag     = "stop"
+	formatFlag   = "format"
+	schemaFlag   = "schema"
+	retriesFlag  = "retries"
+	plainFlag    = "plain"
+	copyCmdFlag  = "copy-command"
+	limitFlag    = "content-limit"
+	sampleFlag   = "csv-sample"
+	itemsFlag    = "json-items"
+	fullPageFlag = "full-page"
+	offlineFlag  = "offline"
+	noCacheFlag  = "no-cache"
+	downloadFlag = "download-limit"
+	blockFlag    = "block-private"
+	allowFlag    = "allow-host"
+	configFlag   = "config"
+	ragFlag      = "rag"
+	ragTopFlag   = "rag-chunks"
+	indexFlag    = "index"
+	cmdTimeFlag  = "command-timeout"
+	noProjFlag   = "no-project"
+	noRedactFlag = "no-redact"
 
-	filePrefix = "FILE:"
-	urlPrefix  = "URL:"
+	pickCommand = "/pick"
 
 	maxTokensDefault = 100 // 40-60 works (4 chars per token)
 	tempDefault      = 0.2
 	topKDefault      = 40
 	topPDefault      = 0.95
+	candDefault      = 1
+	candMax          = 8
+	stopMax          = 5
+	retriesDefault   = 2
 
 	modelContentResponse = "Thank you for the context. What would you like to know?"
+
+	userRole  = "user"
+	modelRole = "model"
 )
 
 var (
 	errStyle = color.New(color.FgRed, color.Bold)
 	aiStyle  = color.New(color.FgGreen, color.Bold)
+
+	aiOut = writerFunc(func(p []byte) (int, error) {
+		return aiStyle.Print(string(p))
+	})
+
+	// diagnostics are written to stderr, so they don't mix with the
+	// responses when the output is redirected
+	errOut io.Writer = color.Error
+
+	// interactive reports whether the prompts are typed in terminal rather
+	// than piped
+	interactive = func() bool { return markdown.IsTerminal(os.Stdin) }
 )
 
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
 type Chat struct {
 	client *genai.Client
 	model  *genai.GenerativeModel
+	vision *genai.GenerativeModel
+
+	apiKey       string
+	temperature  float32
+	maxTokens    int32
+	topK         int32
+	topP         float32
+	candidates   int32
+	stop         []string
+	format       format.Format
+	schema       *format.Schema
+	retries      int
+	render       bool
+	copyCommand  string
+	contentLimit int64
+	csvSample    int
+	jsonItems    int
+	fullPage     bool
+	offline      bool
+	noCache      bool
+	downloadMax  int64
+	blockPrivate bool
+	allowHosts   []string
+	configPath   string
+
+	// credentials used for URL: requests, and commands run by CMD:
+	// without confirmation, from the config file
+	credentials   *url.Credentials
+	allowCommands []string
+	cmdTimeout    int
+
+	// skips loading of the project context
+	noProject bool
+
+	// redacts secrets in content and prompts sent to the model, nil when
+	// redaction is disabled
+	noRedact bool
+	redactor *redact.Redactor
+
+	// loaders of content referenced in prompts (e.g. FILE:path)
+	loaders *content.Registry
 
-	apiKey      string
-	temperature float32
-	maxTokens   int32
-	topK        int32
-	topP        float32
+	// local index of embeddings used to retrieve content for prompts
+	rag       bool
+	ragChunks int
+	indexPath string
+	index     *index.Index
+	embedder  index.Embedder
+
+	session *genai.ChatSession
+	answers []string
+	last    string
+
+	// content loaded into the chat
+	context []*contextItem
+	nextID  int
+
+	// tables loaded from files, queried by /query
+	tables []*table.Table
 }
 
 func (c *Chat) validate() error {
@@ -72,10 +188,31 @@ func (c *Chat) validate() error {
 		return makeErr(maxTokenFlag)
 	}
 
+	if c.candidates < 1 || c.candidates > candMax {
+		return errors.Errorf("chat configuration is invalid: %s must be between 1 and %d", candFlag, candMax)
+	}
+
+	if len(c.stop) > stopMax {
+		return errors.Errorf("chat configuration is invalid: up to %d %s sequences allowed", stopMax, stopFlag)
+	}
+
+	if c.schema != nil && c.format != format.JSON {
+		return errors.Errorf("chat configuration is invalid: %s requires %s %s", schemaFlag, format.JSON, formatFlag)
+	}
+
+	if c.offline && c.noCache {
+		return errors.Errorf("chat configuration is invalid: %s can't be used with %s", offlineFlag, noCacheFlag)
+	}
+
 	return nil
 }
 
 func (c *Chat) Close(_ context.Context) error {
+	if e, ok := c.embedder.(io.Closer); ok {
+		if err := e.Close(); err != nil {
+			return err
+		}
+	}
 	if c.client != nil {
 		return c.client.Close()
 	}
@@ -144,6 +281,268 @@ func (c *Chat) Init(_ context.Context) error {
 		})
 	}
 
+	if flag.Lookup(candFlag) == nil {
+		flag.Func(candFlag, "", func(flagValue string) error {
+			for _, v := range strings.Fields(flagValue) {
+				vv, err := strconv.ParseInt(v, 10, 32)
+				if err != nil {
+					return errors.Wrapf(err, "invalid configuration value for '%s'", candFlag)
+				}
+				c.candidates = int32(vv)
+			}
+			return nil
+		})
+	}
+
+	if flag.Lookup(stopFlag) == nil {
+		flag.Func(stopFlag, "", func(flagValue string) error {
+			if flagValue == "" {
+				return errors.Errorf("invalid configuration value for '%s'", stopFlag)
+			}
+			c.stop = append(c.stop, flagValue)
+			return nil
+		})
+	}
+
+	if flag.Lookup(formatFlag) == nil {
+		flag.Func(formatFlag, "", func(flagValue string) error {
+			f, err := format.Parse(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", formatFlag)
+			}
+			c.format = f
+			return nil
+		})
+	}
+
+	if flag.Lookup(schemaFlag) == nil {
+		flag.Func(schemaFlag, "", func(flagValue string) error {
+			sch, err := format.LoadSchema(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", schemaFlag)
+			}
+			c.schema = sch
+			if c.format == "" {
+				c.format = format.JSON
+			}
+			return nil
+		})
+	}
+
+	if flag.Lookup(retriesFlag) == nil {
+		flag.Func(retriesFlag, "", func(flagValue string) error {
+			for _, v := range strings.Fields(flagValue) {
+				vv, err := strconv.Atoi(v)
+				if err != nil || vv < 0 {
+					return errors.Errorf("invalid configuration value for '%s'", retriesFlag)
+				}
+				c.retries = vv
+			}
+			return nil
+		})
+	}
+
+	if flag.Lookup(plainFlag) == nil {
+		c.render = markdown.IsTerminal(os.Stdout)
+		flag.BoolFunc(plainFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", plainFlag)
+			}
+			c.render = !vv && markdown.IsTerminal(os.Stdout)
+			return nil
+		})
+	}
+
+	if flag.Lookup(fullPageFlag) == nil {
+		flag.BoolFunc(fullPageFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", fullPageFlag)
+			}
+			c.fullPage = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(limitFlag) == nil {
+		flag.Func(limitFlag, "", func(flagValue string) error {
+			for _, v := range strings.Fields(flagValue) {
+				vv, err := strconv.ParseInt(v, 10, 64)
+				if err != nil || vv < 1 {
+					return errors.Errorf("invalid configuration value for '%s'", limitFlag)
+				}
+				c.contentLimit = vv
+			}
+			return nil
+		})
+	}
+
+	if flag.Lookup(sampleFlag) == nil {
+		flag.Func(sampleFlag, "", func(flagValue string) error {
+			for _, v := range strings.Fields(flagValue) {
+				vv, err := strconv.Atoi(v)
+				if err != nil || vv < 1 {
+					return errors.Errorf("invalid configuration value for '%s'", sampleFlag)
+				}
+				c.csvSample = vv
+			}
+			return nil
+		})
+	}
+
+	if flag.Lookup(itemsFlag) == nil {
+		flag.Func(itemsFlag, "", func(flagValue string) error {
+			for _, v := range strings.Fields(flagValue) {
+				vv, err := strconv.Atoi(v)
+				if err != nil || vv < 1 {
+					return errors.Errorf("invalid configuration value for '%s'", itemsFlag)
+				}
+				c.jsonItems = vv
+			}
+			return nil
+		})
+	}
+
+	if flag.Lookup(copyCmdFlag) == nil {
+		flag.Func(copyCmdFlag, "", func(flagValue string) error {
+			if strings.TrimSpace(flagValue) == "" {
+				return errors.Errorf("invalid configuration value for '%s'", copyCmdFlag)
+			}
+			c.copyCommand = flagValue
+			return nil
+		})
+	}
+
+	if flag.Lookup(offlineFlag) == nil {
+		flag.BoolFunc(offlineFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", offlineFlag)
+			}
+			c.offline = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(noCacheFlag) == nil {
+		flag.BoolFunc(noCacheFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", noCacheFlag)
+			}
+			c.noCache = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(downloadFlag) == nil {
+		flag.Func(downloadFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseInt(flagValue, 10, 64)
+			if err != nil || vv < 1 {
+				return errors.Errorf("invalid configuration value for '%s'", downloadFlag)
+			}
+			c.downloadMax = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(blockFlag) == nil {
+		flag.BoolFunc(blockFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", blockFlag)
+			}
+			c.blockPrivate = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(allowFlag) == nil {
+		flag.Func(allowFlag, "", func(flagValue string) error {
+			if strings.TrimSpace(flagValue) == "" {
+				return errors.Errorf("invalid configuration value for '%s'", allowFlag)
+			}
+			c.allowHosts = append(c.allowHosts, flagValue)
+			return nil
+		})
+	}
+
+	if flag.Lookup(configFlag) == nil {
+		flag.Func(configFlag, "", func(flagValue string) error {
+			if strings.TrimSpace(flagValue) == "" {
+				return errors.Errorf("invalid configuration value for '%s'", configFlag)
+			}
+			c.configPath = flagValue
+			return nil
+		})
+	}
+
+	if flag.Lookup(cmdTimeFlag) == nil {
+		flag.Func(cmdTimeFlag, "", func(flagValue string) error {
+			vv, err := strconv.Atoi(flagValue)
+			if err != nil || vv < 1 {
+				return errors.Errorf("invalid configuration value for '%s'", cmdTimeFlag)
+			}
+			c.cmdTimeout = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(noProjFlag) == nil {
+		flag.BoolFunc(noProjFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", noProjFlag)
+			}
+			c.noProject = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(noRedactFlag) == nil {
+		flag.BoolFunc(noRedactFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", noRedactFlag)
+			}
+			c.noRedact = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(ragFlag) == nil {
+		flag.BoolFunc(ragFlag, "", func(flagValue string) error {
+			vv, err := strconv.ParseBool(flagValue)
+			if err != nil {
+				return errors.Wrapf(err, "invalid configuration value for '%s'", ragFlag)
+			}
+			c.rag = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(ragTopFlag) == nil {
+		flag.Func(ragTopFlag, "", func(flagValue string) error {
+			vv, err := strconv.Atoi(flagValue)
+			if err != nil || vv < 1 {
+				return errors.Errorf("invalid configuration value for '%s'", ragTopFlag)
+			}
+			c.ragChunks = vv
+			return nil
+		})
+	}
+
+	if flag.Lookup(indexFlag) == nil {
+		flag.Func(indexFlag, "", func(flagValue string) error {
+			if strings.TrimSpace(flagValue) == "" {
+				return errors.Errorf("invalid configuration value for '%s'", indexFlag)
+			}
+			c.indexPath = flagValue
+			return nil
+		})
+	}
+
 	// defaults
 	if c.apiKey == "" {
 		c.apiKey = os.Getenv(apiKeyEnvVar)
@@ -165,6 +564,42 @@ func (c *Chat) Init(_ context.Context) error {
 		c.topP = topPDefault
 	}
 
+	if c.candidates == 0 {
+		c.candidates = candDefault
+	}
+
+	if c.retries == 0 {
+		c.retries = retriesDefault
+	}
+
+	if c.contentLimit == 0 {
+		c.contentLimit = file.MaxSizeDefault / 1024
+	}
+
+	if c.csvSample == 0 {
+		c.csvSample = table.SampleDefault
+	}
+
+	if c.jsonItems == 0 {
+		c.jsonItems = structured.ItemsDefault
+	}
+
+	if c.copyCommand == "" {
+		c.copyCommand = defaultCopyCommand()
+	}
+
+	if c.cmdTimeout == 0 {
+		c.cmdTimeout = int(shell.TimeoutDefault / time.Second)
+	}
+
+	if c.ragChunks == 0 {
+		c.ragChunks = index.TopDefault
+	}
+
+	if c.downloadMax == 0 {
+		c.downloadMax = url.MaxSizeDefault / (1024 * 1024)
+	}
+
 	return nil
 }
 
@@ -178,80 +613,57 @@ func (c *Chat) Start(ctx context.Context, scanner *bufio.Scanner) error {
 		return errors.New("missing scanner parameter")
 	}
 
+	// config
+	if err := c.loadConfig(); err != nil {
+		return err
+	}
+
 	// model
 	if err := c.setup(ctx); err != nil {
 		return err
 	}
 
-	// chat
-	cs := c.model.StartChat()
-
-	// send
-	send := func(msg string) {
-		// results
-		iter := cs.SendMessageStream(ctx, genai.Text(msg))
-		for {
-			res, err := iter.Next()
-			if errors.Is(err, iterator.Done) {
-				break
-			}
-			if err != nil {
-				errStyle.Printf("error processing your prompt: %s\n", errors.Unwrap(err).Error())
-				break
-			}
-			for _, c := range res.Candidates {
-				if c.Content != nil {
-					for _, p := range c.Content.Parts {
-						aiStyle.Print(p)
-					}
-				}
-			}
-		}
-		aiStyle.Println()
+	// loaders
+	opt, err := c.urlOptions()
+	if err != nil {
+		return err
 	}
+	c.loaders = loaders.New(loaders.Options{
+		MaxSize: c.contentLimit * 1024,
+		Sample:  c.csvSample,
+		Items:   c.jsonItems,
+		URL:     opt,
 
-	// load history
-	load := func(msg string) {
-		h := []*genai.Content{
-			{
-				Parts: []genai.Part{genai.Text(msg)},
-				Role:  "user",
-			},
-			{
-				Parts: []genai.Part{genai.Text(modelContentResponse)},
-				Role:  "model",
-			},
-		}
-		cs.History = append(cs.History, h...)
-		aiStyle.Printf("%s:\n", modelContentResponse)
-	}
+		CommandTimeout: time.Duration(c.cmdTimeout) * time.Second,
+		AllowCommands:  c.allowCommands,
+	})
 
-	// files
-	readFile := func(f string) error {
-		aiStyle.Printf("Describe content of %s:\n", f)
-		scanner.Scan()
-		txt, err := file.GetContent(scanner.Text(), f)
-		if err != nil {
-			return errors.Wrapf(err, "error reading file: %s", f)
+	// index
+	if c.rag {
+		if err := c.openIndex(ctx); err != nil {
+			return err
 		}
-		load(txt)
-		return nil
 	}
 
-	// urls
-	readURL := func(u string) error {
-		aiStyle.Printf("Describe content of %s:\n", u)
-		scanner.Scan()
-		txt, err := url.GetContent(scanner.Text(), u)
+	// chat
+	c.session = c.model.StartChat()
+
+	// project
+	if !c.noProject {
+		// the chat starts without the project when it can't be read
+		p, err := project.Find(".")
 		if err != nil {
-			return errors.Wrapf(err, "error reading URL: %s", u)
+			errStyle.Fprintln(errOut, err.Error())
+		}
+		if p != nil {
+			c.loadProject(ctx, p, scanner)
 		}
-		load(txt)
-		return nil
 	}
 
-	// prompt
-	aiStyle.Println("How can I help?")
+	// prompt, skipped when input is piped so output can be parsed
+	if markdown.IsTerminal(os.Stdin) {
+		aiStyle.Println("How can I help?")
+	}
 	for {
 		scanner.Scan()
 		text := scanner.Text()
@@ -259,21 +671,59 @@ func (c *Chat) Start(ctx context.Context, scanner *bufio.Scanner) error {
 			break
 		}
 
-		if strings.HasPrefix(text, filePrefix) {
-			if err := readFile(text[len(filePrefix):]); err != nil {
-				errStyle.Println(err.Error())
+		if l, location, ok := c.loaders.Find(text); ok {
+			if err := c.readContent(ctx, l, location, scanner); err != nil {
+				errStyle.Fprintln(errOut, err.Error())
+			}
+			continue
+		}
+
+		if strings.HasPrefix(text, codeCommand) {
+			if err := c.code(text[len(codeCommand):], scanner); err != nil {
+				errStyle.Fprintln(errOut, err.Error())
+			}
+			continue
+		}
+
+		if strings.HasPrefix(text, queryCommand) {
+			if err := c.query(ctx, text[len(queryCommand):]); err != nil {
+				errStyle.Fprintln(errOut, err.Error())
+			}
+			aiStyle.Println()
+			continue
+		}
+
+		if strings.HasPrefix(text, contextCommand) {
+			if err := c.contextCmd(ctx, text[len(contextCommand):], scanner); err != nil {
+				errStyle.Fprintln(errOut, err.Error())
 			}
 			continue
 		}
 
-		if strings.HasPrefix(text, urlPrefix) {
-			if err := readURL(text[len(urlPrefix):]); err != nil {
-				errStyle.Println(err.Error())
+		if strings.HasPrefix(text, pickCommand) {
+			if err := c.pick(text[len(pickCommand):]); err != nil {
+				errStyle.Fprintln(errOut, err.Error())
 			}
 			continue
 		}
 
-		send(text)
+		text = c.redactText("the prompt", text)
+
+		if c.rag {
+			prompt, err := c.retrieve(ctx, text)
+			if err != nil {
+				errStyle.Fprintln(errOut, err.Error())
+			} else {
+				text = prompt
+			}
+		}
+
+		if err := c.send(ctx, text); err != nil {
+			// in schema mode invalid output is fatal so scripts can rely on exit code
+			if c.schema != nil {
+				return err
+			}
+		}
 		aiStyle.Println()
 	}
 
@@ -285,6 +735,377 @@ func (c *Chat) Start(ctx context.Context, scanner *bufio.Scanner) error {
 	return nil
 }
 
+// send generates the configured number of candidate answers for msg and
+// records the first one in the chat history. Without history, all the
+// candidates are generated in single request. The chat session always asks
+// the API for single candidate, so with history the alternatives are
+// generated as separate requests over the same history. Candidates are
+// printed one after another, those that don't match the response format are discarded, send returns error
+// only when none of them does. The loaded content is sent before the chat
+// history. Prompts with attached images are sent to the vision model
+// along with the loaded content, but without the chat history, which it
+// doesn't support.
+func (c *Chat) send(ctx context.Context, msg string) error {
+	history := c.session.History
+	answers := make([]string, 0, c.candidates)
+
+	prompt := msg
+	if c.schema != nil {
+		prompt = fmt.Sprintf("%s\n\n%s", msg, c.schema.Instruction())
+	} else if c.format != "" {
+		prompt = fmt.Sprintf("%s\n\n%s", msg, c.format.Instruction())
+	}
+
+	model, hist := c.model, append(c.contextHistory(), history...)
+	parts, text := toParts(c.attachments())
+	parts = append(parts, genai.Text(prompt))
+	if len(parts) > len(text)+1 {
+		model, hist = c.vision, nil
+		parts = append(c.contextParts(), parts...)
+		if len(history) > 0 {
+			aiStyle.Println("The chat history isn't sent with images, only the loaded content.")
+		}
+	}
+
+	var lastErr error
+	if c.candidates > 1 && len(hist) == 0 {
+		answers, lastErr = c.generateAll(ctx, model, parts)
+	} else {
+		for i := int32(0); i < c.candidates; i++ {
+			if c.candidates > 1 {
+				aiStyle.Printf("[candidate %d of %d]\n", i+1, c.candidates)
+			}
+
+			txt, err := c.generate(ctx, model, hist, parts)
+			if err != nil {
+				errStyle.Fprintln(errOut, err.Error())
+				lastErr = err
+				continue
+			}
+
+			aiStyle.Println()
+			answers = append(answers, txt)
+		}
+	}
+
+	if len(answers) == 0 {
+		return lastErr
+	}
+
+	c.answers = answers
+	c.last = answers[0]
+	c.sent()
+	c.session.History = append(history,
+		&genai.Content{Parts: append(text, genai.Text(prompt)), Role: userRole},
+		textContent(modelRole, answers[0]))
+
+	if len(answers) > 1 {
+		aiStyle.Printf("Using candidate 1, type '%s N' to use another one.\n", pickCommand)
+	}
+
+	return nil
+}
+
+// generate sends parts in a new chat session over the provided history and
+// returns the response validated against the response format (see
+// repair).
+func (c *Chat) generate(ctx context.Context, model *genai.GenerativeModel, history []*genai.Content, parts []genai.Part) (string, error) {
+	cs := model.StartChat()
+	cs.History = append([]*genai.Content{}, history...)
+
+	txt, err := stream(ctx, cs, c.output(), parts...)
+	if err != nil {
+		return "", errors.Wrap(err, "error processing your prompt")
+	}
+
+	if c.format == "" {
+		return txt, nil
+	}
+
+	return c.repair(txt, c.resend(ctx, cs))
+}
+
+// generateAll generates all the candidates for parts in single request
+// using the candidate count of the model, and returns those which match
+// the response format (see repair).
+func (c *Chat) generateAll(ctx context.Context, model *genai.GenerativeModel, parts []genai.Part) ([]string, error) {
+	res, err := model.GenerateContent(ctx, parts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error processing your prompt")
+	}
+
+	answers := make([]string, 0, len(res.Candidates))
+	lastErr := errors.New("error processing your prompt: no candidates")
+	for i, cand := range res.Candidates {
+		aiStyle.Printf("[candidate %d of %d]\n", i+1, len(res.Candidates))
+
+		txt := candidateText(cand)
+		if c.format == "" {
+			err = writeOut(c.display(), txt)
+		} else {
+			cs := model.StartChat()
+			cs.History = []*genai.Content{{Parts: parts, Role: userRole}, textContent(modelRole, txt)}
+			txt, err = c.repair(txt, c.resend(ctx, cs))
+		}
+		if err != nil {
+			errStyle.Fprintln(errOut, err.Error())
+			lastErr = err
+			continue
+		}
+
+		aiStyle.Println()
+		answers = append(answers, txt)
+	}
+
+	if len(answers) == 0 {
+		return nil, lastErr
+	}
+	return answers, nil
+}
+
+// resend returns function which sends the repair prompt to the chat
+// session (see repair).
+func (c *Chat) resend(ctx context.Context, cs *genai.ChatSession) func(prompt string) (string, error) {
+	return func(prompt string) (string, error) {
+		txt, err := stream(ctx, cs, c.output(), genai.Text(prompt))
+		if err != nil {
+			return "", errors.Wrap(err, "error processing your prompt")
+		}
+		return txt, nil
+	}
+}
+
+// repair validates txt and prints it once valid. When schema is set,
+// invalid responses are sent back using resend with the validation errors,
+// up to the configured number of retries.
+func (c *Chat) repair(txt string, resend func(prompt string) (string, error)) (string, error) {
+	for i := 0; ; i++ {
+		v, err := c.check(txt)
+		if err == nil {
+			if err := writeOut(c.display(), v); err != nil {
+				return "", err
+			}
+			return v, nil
+		}
+
+		if c.schema == nil || i >= c.retries {
+			return "", errors.Wrapf(err, "invalid %s response", c.format)
+		}
+
+		errStyle.Fprintf(errOut, "%s, retrying (%d of %d)\n", err.Error(), i+1, c.retries)
+		if txt, err = resend(repairPrompt(err)); err != nil {
+			return "", err
+		}
+	}
+}
+
+// output returns the writer to which the streamed response is printed.
+// Responses in the requested format are printed only once they've been
+// validated, so the discarded candidates are never shown.
+func (c *Chat) output() io.Writer {
+	if c.format != "" {
+		return io.Discard
+	}
+	return c.display()
+}
+
+// display returns the writer to which the response is printed.
+func (c *Chat) display() io.Writer {
+	switch {
+	case c.format == format.JSON:
+		// JSON is printed as is, so it can be parsed
+		return aiOut
+	case c.render:
+		return markdown.NewRenderer(color.Output, markdown.Width(os.Stdout), aiStyle)
+	default:
+		return aiOut
+	}
+}
+
+// check validates txt against the response format and schema, and returns
+// it in its normalized form.
+func (c *Chat) check(txt string) (string, error) {
+	v, err := c.format.Validate(txt)
+	if err != nil {
+		return "", err
+	}
+
+	if c.schema != nil {
+		if err := c.schema.Validate([]byte(v)); err != nil {
+			return "", err
+		}
+	}
+
+	return v, nil
+}
+
+func repairPrompt(err error) string {
+	var sb strings.Builder
+	sb.WriteString("Your previous response was not valid:\n")
+
+	var verr *format.ValidationError
+	if errors.As(err, &verr) {
+		for _, e := range verr.Errors {
+			sb.WriteString("- ")
+			sb.WriteString(e)
+			sb.WriteString("\n")
+		}
+	} else {
+		sb.WriteString("- ")
+		sb.WriteString(err.Error())
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("Respond again with only the corrected JSON.")
+	return sb.String()
+}
+
+// pick replaces the last model turn in the chat history with the selected
+// candidate from the most recent prompt.
+func (c *Chat) pick(arg string) error {
+	if len(c.answers) < 2 {
+		return errors.New("no candidates to pick from")
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 1 || n > len(c.answers) {
+		return errors.Errorf("invalid candidate, expected number between 1 and %d", len(c.answers))
+	}
+
+	c.session.History[len(c.session.History)-1] = textContent(modelRole, c.answers[n-1])
+	c.last = c.answers[n-1]
+	aiStyle.Printf("Using candidate %d.\n", n)
+
+	return nil
+}
+
+// writeOut writes txt to out, flushing writers which buffer the output.
+func writeOut(out io.Writer, txt string) error {
+	if _, err := io.WriteString(out, txt); err != nil {
+		return errors.Wrap(err, "error writing response")
+	}
+	if f, ok := out.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return errors.Wrap(err, "error writing response")
+		}
+	}
+	return nil
+}
+
+// stream sends parts to the chat session, writes the response to out as it
+// arrives, and returns the complete response text. Writers which buffer
+// the output (e.g. Markdown renderer) are flushed at the end.
+func stream(ctx context.Context, cs *genai.ChatSession, out io.Writer, parts ...genai.Part) (string, error) {
+	var sb strings.Builder
+	iter := cs.SendMessageStream(ctx, parts...)
+	for {
+		res, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, c := range res.Candidates {
+			if c.Content == nil {
+				continue
+			}
+			for _, p := range c.Content.Parts {
+				if t, ok := p.(genai.Text); ok {
+					if _, err := io.WriteString(out, string(t)); err != nil {
+						return "", errors.Wrap(err, "error writing response")
+					}
+					sb.WriteString(string(t))
+				}
+			}
+		}
+	}
+	if f, ok := out.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return "", errors.Wrap(err, "error writing response")
+		}
+	}
+	return sb.String(), nil
+}
+
+// candidateText returns the text parts of the candidate.
+func candidateText(cand *genai.Candidate) string {
+	if cand.Content == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, p := range cand.Content.Parts {
+		if t, ok := p.(genai.Text); ok {
+			sb.WriteString(string(t))
+		}
+	}
+	return sb.String()
+}
+
+// toParts converts content parts to genai parts, and returns all the parts
+// as well as only the text ones.
+func toParts(list []content.Part) (all []genai.Part, text []genai.Part) {
+	for _, p := range list {
+		switch v := p.(type) {
+		case content.Text:
+			all = append(all, genai.Text(v))
+			text = append(text, genai.Text(v))
+		case *content.Blob:
+			all = append(all, genai.Blob{MIMEType: v.MIMEType, Data: v.Data})
+		}
+	}
+	return all, text
+}
+
+func textContent(role, txt string) *genai.Content {
+	return &genai.Content{
+		Parts: []genai.Part{genai.Text(txt)},
+		Role:  role,
+	}
+}
+
+// loadConfig loads the configuration file.
+func (c *Chat) loadConfig() error {
+	cfg, err := config.Load(c.configPath)
+	if err != nil {
+		return err
+	}
+	if c.credentials, err = cfg.Credentials(); err != nil {
+		return err
+	}
+	c.allowCommands = cfg.Commands.Allow
+	if !c.noRedact {
+		if c.redactor, err = cfg.Redactor(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// urlOptions returns the options for loading URL: content.
+func (c *Chat) urlOptions() (url.Options, error) {
+	opt := url.Options{
+		FullPage: c.fullPage,
+		Sample:   c.csvSample,
+		Budget:   int(c.contentLimit * 1024),
+		Items:    c.jsonItems,
+		Offline:  c.offline,
+		MaxSize:  c.downloadMax * 1024 * 1024,
+
+		BlockPrivate: c.blockPrivate,
+		Allow:        c.allowHosts,
+		Credentials:  c.credentials,
+	}
+	if !c.noCache {
+		cache, err := url.DefaultCache()
+		if err != nil {
+			return opt, err
+		}
+		opt.Cache = cache
+	}
+	return opt, nil
+}
+
 func (c *Chat) setup(ctx context.Context) error {
 	// client
 	client, err := genai.NewClient(ctx, option.WithAPIKey(c.apiKey))
@@ -293,12 +1114,23 @@ func (c *Chat) setup(ctx context.Context) error {
 	}
 	c.client = client
 
-	// model
-	model := client.GenerativeModel(modelType)
+	// models
+	c.model = c.newModel(modelType)
+	c.vision = c.newModel(visionModelType)
+
+	return nil
+}
+
+func (c *Chat) newModel(name string) *genai.GenerativeModel {
+	model := c.client.GenerativeModel(name)
 	model.SetTemperature(c.temperature)
 	model.SetMaxOutputTokens(c.maxTokens)
 	model.SetTopK(c.topK)
 	model.SetTopP(c.topP)
+	// chat sessions always ask for single candidate, the count applies to
+	// requests without history (see send)
+	model.SetCandidateCount(c.candidates)
+	model.StopSequences = c.stop
 	model.SafetySettings = []*genai.SafetySetting{
 		{
 			Category:  genai.HarmCategoryDangerousContent,
@@ -317,8 +1149,5 @@ func (c *Chat) setup(ctx context.Context) error {
 			Threshold: genai.HarmBlockNone,
 		},
 	}
-
-	c.model = model
-
-	return nil
+	return model
 }
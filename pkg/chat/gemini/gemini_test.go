@@ -2,10 +2,15 @@ package gemini
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"os"
 	"testing"
 
+	"github.com/fatih/color"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/format"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +46,88 @@ func TestChat(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestPick(t *testing.T) {
+	c := Chat{
+		session: &genai.ChatSession{
+			History: []*genai.Content{
+				textContent(userRole, "q"),
+				textContent(modelRole, "a1"),
+			},
+		},
+	}
+
+	t.Run("Without candidates", func(t *testing.T) {
+		assert.Error(t, c.pick("1"))
+	})
+
+	c.answers = []string{"a1", "a2"}
+
+	t.Run("Invalid candidate", func(t *testing.T) {
+		assert.Error(t, c.pick("x"))
+		assert.Error(t, c.pick("3"))
+	})
+
+	t.Run("Valid candidate", func(t *testing.T) {
+		assert.NoError(t, c.pick(" 2"))
+		assert.Len(t, c.session.History, 2)
+		assert.Equal(t, genai.Text("a2"), c.session.History[1].Parts[0])
+	})
+}
+
+func TestToParts(t *testing.T) {
+	all, text := toParts([]content.Part{
+		content.Text("desc"),
+		&content.Blob{MIMEType: "image/png", Data: []byte("png")},
+	})
+	assert.Equal(t, []genai.Part{
+		genai.Text("desc"),
+		genai.Blob{MIMEType: "image/png", Data: []byte("png")},
+	}, all)
+	assert.Equal(t, []genai.Part{genai.Text("desc")}, text)
+}
+
+func TestRepair(t *testing.T) {
+	schema, err := format.NewSchema([]byte(`{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`))
+	assert.NoError(t, err)
+	c := Chat{format: format.JSON, schema: schema, retries: 2}
+
+	var diag bytes.Buffer
+	errOut = &diag
+	defer func() { errOut = color.Error }()
+
+	t.Run("Repaired", func(t *testing.T) {
+		diag.Reset()
+		var prompts []string
+		v, err := c.repair(`{"id": 1}`, func(prompt string) (string, error) {
+			prompts = append(prompts, prompt)
+			return "```json\n{\"name\": \"Austin\"}\n```", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name": "Austin"}`, v)
+		assert.Len(t, prompts, 1)
+		assert.Contains(t, prompts[0], "Your previous response was not valid:\n- ")
+		assert.Contains(t, diag.String(), "retrying (1 of 2)")
+	})
+
+	t.Run("Retries exceeded", func(t *testing.T) {
+		diag.Reset()
+		n := 0
+		_, err := c.repair("not json", func(string) (string, error) {
+			n++
+			return "still not json", nil
+		})
+		assert.ErrorContains(t, err, "invalid json response")
+		assert.Equal(t, 2, n)
+		assert.Contains(t, diag.String(), "retrying (2 of 2)")
+	})
+
+	t.Run("Without schema", func(t *testing.T) {
+		c := Chat{format: format.JSON, retries: 2}
+		_, err := c.repair("not json", func(string) (string, error) {
+			t.Fatal("resent without schema")
+			return "", nil
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,292 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	gl "cloud.google.com/go/ai/generativelanguage/apiv1"
+	pb "cloud.google.com/go/ai/generativelanguage/apiv1/generativelanguagepb"
+	"github.com/google/generative-ai-go/genai"
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/content/file"
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/mchmarny/aictl/pkg/index"
+	"github.com/mchmarny/aictl/pkg/redact"
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+)
+
+const (
+	embeddingModelType = "embedding-001"
+	// maximum number of texts embedded in single request
+	embeddingBatchMax = 100
+	indexUsage         = "usage: index add <paths|urls>, index search \"query\", index ls, or index rm <source>"
+
+	// length of chunk text printed by index search
+	excerptMax = 300
+)
+
+// embedder computes embeddings using the GenAI embedding model. The GenAI
+// client doesn't support batch embedding, so documents are embedded using
+// the API client directly.
+type embedder struct {
+	doc   *gl.GenerativeClient
+	query *genai.EmbeddingModel
+}
+
+func newEmbedder(ctx context.Context, client *genai.Client, apiKey string) (*embedder, error) {
+	doc, err := gl.NewGenerativeRESTClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating embedding client: %s", err.Error())
+	}
+	e := &embedder{
+		doc:   doc,
+		query: client.EmbeddingModel(embeddingModelType),
+	}
+	e.query.TaskType = genai.TaskTypeRetrievalQuery
+	return e, nil
+}
+
+func (e *embedder) Model() string {
+	return embeddingModelType
+}
+
+// EmbedDocuments embeds the texts in batches, each batch in single
+// request.
+func (e *embedder) EmbedDocuments(ctx context.Context, title string, texts []string) ([][]float32, error) {
+	taskType := pb.TaskType_RETRIEVAL_DOCUMENT
+	var list [][]float32
+	for start := 0; start < len(texts); start += embeddingBatchMax {
+		req := &pb.BatchEmbedContentsRequest{Model: "models/" + embeddingModelType}
+		for _, t := range texts[start:min(start+embeddingBatchMax, len(texts))] {
+			r := &pb.EmbedContentRequest{
+				Model:    req.Model,
+				Content:  &pb.Content{Parts: []*pb.Part{{Data: &pb.Part_Text{Text: t}}}},
+				TaskType: &taskType,
+			}
+			if title != "" {
+				r.Title = &title
+			}
+			req.Requests = append(req.Requests, r)
+		}
+
+		resp, err := e.doc.BatchEmbedContents(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Embeddings) != len(req.Requests) {
+			return nil, errors.Errorf("expected %d embeddings, got %d", len(req.Requests), len(resp.Embeddings))
+		}
+		for _, emb := range resp.Embeddings {
+			if len(emb.GetValues()) == 0 {
+				return nil, errors.New("empty embedding")
+			}
+			list = append(list, emb.GetValues())
+		}
+	}
+	return list, nil
+}
+
+func (e *embedder) Close() error {
+	return e.doc.Close()
+}
+
+func (e *embedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return embed(e.query.EmbedContent(ctx, genai.Text(text)))
+}
+
+func embed(resp *genai.EmbedContentResponse, err error) ([]float32, error) {
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Embedding == nil || len(resp.Embedding.Values) == 0 {
+		return nil, errors.New("empty embedding")
+	}
+	return resp.Embedding.Values, nil
+}
+
+// openIndex opens the local index and creates the embedder.
+func (c *Chat) openIndex(ctx context.Context) error {
+	path := c.indexPath
+	if path == "" {
+		var err error
+		if path, err = index.DefaultPath(); err != nil {
+			return err
+		}
+	}
+	ix, err := index.Open(path)
+	if err != nil {
+		return err
+	}
+	if c.client == nil {
+		if err := c.setup(ctx); err != nil {
+			return err
+		}
+	}
+	e, err := newEmbedder(ctx, c.client, c.apiKey)
+	if err != nil {
+		return err
+	}
+	c.index = ix
+	c.embedder = e
+	return nil
+}
+
+// Index runs the index command: add indexes the content of files or urls,
+// search prints the chunks most similar to the query, ls lists the indexed
+// content, and rm removes it from the index.
+func (c *Chat) Index(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(indexUsage)
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if err := c.loadConfig(); err != nil {
+		return err
+	}
+	if err := c.openIndex(ctx); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			return errors.New(indexUsage)
+		}
+		for _, src := range args[1:] {
+			if err := c.indexSource(ctx, src, w); err != nil {
+				return err
+			}
+		}
+		return c.index.Save()
+	case "search":
+		if len(args) < 2 {
+			return errors.New(indexUsage)
+		}
+		list, err := c.index.Search(ctx, c.embedder, strings.Join(args[1:], " "), c.ragChunks)
+		if err != nil {
+			return err
+		}
+		for i, r := range list {
+			fmt.Fprintf(w, "[%d] %s (score: %.3f)\n%s\n\n", i+1, url.Redact(r.Source), r.Score, excerpt(r.Text))
+		}
+		return nil
+	case "ls":
+		for _, s := range c.index.Sources() {
+			fmt.Fprintf(w, "%s\t%d chunks\n", url.Redact(s.Source), s.Chunks)
+		}
+		return nil
+	case "rm":
+		if len(args) < 2 {
+			return errors.New(indexUsage)
+		}
+		for _, src := range args[1:] {
+			src = url.Redact(src)
+			fmt.Fprintf(w, "Removed %d chunks of %s.\n", c.index.Remove(src), src)
+		}
+		return c.index.Save()
+	}
+	return errors.Errorf("unknown index command: %s, %s", args[0], indexUsage)
+}
+
+// indexSource adds the content of url, or of the files selected by path
+// (each file as separate source), to the index.
+func (c *Chat) indexSource(ctx context.Context, src string, w io.Writer) error {
+	add := func(source, text string) error {
+		// credentials in url aren't stored in the index
+		source = url.Redact(source)
+		if c.redactor != nil {
+			// printed before the content is sent to the embedding model
+			var redacted redact.Summary
+			if text, redacted = c.redactor.Text(text); len(redacted) > 0 {
+				fmt.Fprintf(w, "Redacted %s from %s.\n", redacted, source)
+			}
+		}
+		n, err := c.index.Add(ctx, c.embedder, source, "", text)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Indexed %s (%d chunks).\n", source, n)
+		return nil
+	}
+
+	if strings.HasPrefix(src, "http") {
+		opt, err := c.urlOptions()
+		if err != nil {
+			return err
+		}
+		p, err := url.Get("", src, opt)
+		if err != nil {
+			return errors.Wrapf(err, "error reading URL: %s", url.Redact(src))
+		}
+		txt, err := content.JoinText(p.Parts)
+		if err != nil {
+			return errors.Wrapf(err, "error indexing URL: %s", url.Redact(src))
+		}
+		return add(src, txt)
+	}
+
+	sel, err := file.Select(src, 0)
+	if err != nil {
+		return err
+	}
+	sel.Sample = c.csvSample
+	sel.Items = c.jsonItems
+	for _, s := range sel.Skipped {
+		fmt.Fprintf(w, "Skipped %s (%s).\n", s.Path, s.Reason)
+	}
+	for _, f := range sel.Files {
+		fs := sel
+		if len(sel.Files) > 1 {
+			if fs, err = file.Select(f, 0); err != nil {
+				return err
+			}
+			fs.Sample = c.csvSample
+			fs.Items = c.jsonItems
+		}
+		txt, err := fs.Content("")
+		if err != nil {
+			// images can't be indexed
+			fmt.Fprintf(w, "Skipped %s (%s).\n", f, err.Error())
+			continue
+		}
+		if err := add(f, txt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retrieve returns the prompt preceded by the indexed chunks most similar
+// to it, along with their sources.
+func (c *Chat) retrieve(ctx context.Context, prompt string) (string, error) {
+	list, err := c.index.Search(ctx, c.embedder, prompt, c.ragChunks)
+	if err != nil {
+		return "", errors.Wrap(err, "error searching index")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Use the following excerpts when they are relevant to the question, and cite their sources (e.g. [1]):\n\n")
+	sources := make([]string, 0, len(list))
+	for i, r := range list {
+		source := fmt.Sprintf("[%d] %s", i+1, url.Redact(r.Source))
+		fmt.Fprintf(&sb, "%s\n%s\n\n", source, r.Text)
+		sources = append(sources, source)
+	}
+	fmt.Fprintf(&sb, "Question: %s", prompt)
+
+	aiStyle.Printf("Retrieved %d chunks from the index:\n  %s\n", len(list), strings.Join(sources, "\n  "))
+	return sb.String(), nil
+}
+
+// excerpt returns the beginning of the text on single line.
+func excerpt(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > excerptMax {
+		return string(r[:excerptMax]) + "..."
+	}
+	return s
+}
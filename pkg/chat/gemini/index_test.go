@@ -0,0 +1,116 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/mchmarny/aictl/pkg/index"
+	"github.com/mchmarny/aictl/pkg/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+// letters embeds text as counts of its letters.
+type letters struct{}
+
+func (letters) Model() string {
+	return "letters"
+}
+
+func (l letters) EmbedDocuments(ctx context.Context, _ string, texts []string) ([][]float32, error) {
+	var list [][]float32
+	for _, t := range texts {
+		v, _ := l.EmbedQuery(ctx, t)
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (letters) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	v := make([]float32, 26)
+	for _, r := range strings.ToLower(text) {
+		if r >= 'a' && r <= 'z' {
+			v[r-'a']++
+		}
+	}
+	return v, nil
+}
+
+// recorder embeds using letters, recording the output written before the
+// texts are embedded.
+type recorder struct {
+	letters
+	out    *bytes.Buffer
+	before []string
+}
+
+func (r *recorder) EmbedDocuments(ctx context.Context, title string, texts []string) ([][]float32, error) {
+	r.before = append(r.before, r.out.String())
+	return r.letters.EmbedDocuments(ctx, title, texts)
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("zzz zzz"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.md"), []byte("aaa bbb"), 0o644))
+
+	ix, err := index.Open(filepath.Join(dir, "index.json"))
+	assert.NoError(t, err)
+	c := Chat{index: ix, embedder: letters{}, ragChunks: 1}
+
+	var out bytes.Buffer
+	assert.NoError(t, c.indexSource(ctx, dir+"/*.md", &out))
+	assert.Contains(t, out.String(), "Indexed "+filepath.Join(dir, "a.md")+" (1 chunks).")
+	assert.Len(t, ix.Sources(), 2)
+
+	p, err := c.retrieve(ctx, "zz?")
+	assert.NoError(t, err)
+	assert.Contains(t, p, "[1] "+filepath.Join(dir, "a.md")+"\nzzz zzz\n\nQuestion: zz?")
+	assert.NotContains(t, p, "aaa")
+
+	t.Run("Credentials in URL", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("yyy yyy"))
+		}))
+		defer srv.Close()
+		c.noCache = true
+
+		out.Reset()
+		src := strings.Replace(srv.URL, "://", "://user:secret@", 1) + "/doc.txt"
+		assert.NoError(t, c.indexSource(ctx, src, &out))
+		assert.NotContains(t, out.String(), "secret")
+		for _, s := range ix.Sources() {
+			assert.NotContains(t, s.Source, "secret")
+		}
+
+		p, err := c.retrieve(ctx, "yy")
+		assert.NoError(t, err)
+		assert.Contains(t, p, "[1] "+url.Redact(src)+"\n")
+		assert.NotContains(t, p, "secret")
+
+		assert.Equal(t, 1, ix.Remove(url.Redact(src)))
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		r, err := redact.New(nil, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "c.md"), []byte("mail jane@example.com"), 0o644))
+
+		var out bytes.Buffer
+		e := &recorder{out: &out}
+		c := Chat{index: ix, embedder: e, redactor: r}
+		assert.NoError(t, c.indexSource(ctx, filepath.Join(dir, "c.md"), &out))
+		// the summary is printed before the content is embedded
+		assert.Equal(t, []string{"Redacted email address (1) from " + filepath.Join(dir, "c.md") + ".\n"}, e.before)
+	})
+
+	assert.Equal(t, "a b", excerpt("a\n\n b"))
+	assert.Equal(t, strings.Repeat("a", excerptMax)+"...", excerpt(strings.Repeat("a", excerptMax+1)))
+}
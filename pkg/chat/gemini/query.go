@@ -0,0 +1,117 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/mchmarny/aictl/pkg/query"
+	"github.com/pkg/errors"
+)
+
+const (
+	queryCommand = "/query"
+	queryUsage   = "usage: /query question about the loaded CSV files"
+
+	// maximum number of result rows printed and sent to the model
+	queryRowsMax = 50
+)
+
+// addTables keeps the tables loaded from files for /query, replacing
+// previously loaded tables with the same name.
+func (c *Chat) addTables(tables []*table.Table) {
+	for _, t := range tables {
+		replaced := false
+		for i, old := range c.tables {
+			if old.Name == t.Name {
+				c.tables[i] = t
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.tables = append(c.tables, t)
+		}
+	}
+}
+
+// query answers question about the loaded tables. The model writes SQL
+// query which is executed locally over the complete data, and then the
+// model phrases the answer using the query result. Queries which fail
+// are sent back to the model with the error up to the configured number
+// of retries. The question, example values, and result are redacted before
+// they're sent to the model, and the placeholders in the query are
+// replaced by the original values before it's executed.
+func (c *Chat) query(ctx context.Context, question string) error {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return errors.New(queryUsage)
+	}
+	if len(c.tables) == 0 {
+		return errors.New("no tables loaded, use FILE: to load CSV file first")
+	}
+
+	cs := c.model.StartChat()
+	msg := c.redactText("the query", queryPrompt(c.tables, question))
+
+	var sql string
+	var res *query.Result
+	for i := 0; ; i++ {
+		txt, err := stream(ctx, cs, io.Discard, genai.Text(msg))
+		if err != nil {
+			return errors.Wrap(err, "error generating query")
+		}
+
+		sql = c.restore(query.Extract(txt))
+		aiStyle.Printf("Query: %s\n", sql)
+
+		if res, err = query.Run(sql, c.tables); err == nil {
+			break
+		}
+		if i >= c.retries {
+			return err
+		}
+
+		errStyle.Fprintf(errOut, "%s, retrying (%d of %d)\n", err.Error(), i+1, c.retries)
+		msg = c.redactText("the query error", fmt.Sprintf("The query failed: %s\nRespond again with only the corrected SQL query.", err.Error()))
+	}
+
+	result := res.Markdown(queryRowsMax)
+	if err := writeOut(c.display(), result); err != nil {
+		return err
+	}
+	aiStyle.Println()
+
+	return c.send(ctx, c.redactText("the query result", answerPrompt(question, sql, result)))
+}
+
+func queryPrompt(tables []*table.Table, question string) string {
+	var sb strings.Builder
+	sb.WriteString("Write single SQL SELECT query which answers the question below using these tables:\n\n")
+	for _, t := range tables {
+		fmt.Fprintf(&sb, "Table %s (%d rows):\n", query.TableName(t), len(t.Rows))
+		for i, col := range t.Columns {
+			example := ""
+			if len(t.Rows) > 0 {
+				example = t.Rows[0][i]
+			}
+			fmt.Fprintf(&sb, "- \"%s\" %s, e.g. %q\n", col.Name, col.Type, example)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("The query runs over single table (no joins or subqueries) and can use WHERE, ")
+	sb.WriteString("GROUP BY, HAVING, ORDER BY, LIMIT, DISTINCT, LIKE, IN, BETWEEN, IS NULL, ")
+	sb.WriteString("COUNT, SUM, AVG, MIN, MAX, ROUND, ABS, LOWER, UPPER, LENGTH, TRIM, SUBSTR and COALESCE. ")
+	sb.WriteString("Quote column names in double quotes. Dates are strings in the format shown above.\n\n")
+	fmt.Fprintf(&sb, "Question: %s\n\n", question)
+	sb.WriteString("Respond with only the SQL query.")
+	return sb.String()
+}
+
+func answerPrompt(question, sql, result string) string {
+	return fmt.Sprintf("Answer the question using the result of the query executed over the complete data.\n\n"+
+		"Question: %s\n\nQuery: %s\n\nResult:\n%s", question, sql, result)
+}
@@ -0,0 +1,63 @@
+package gemini
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/mchmarny/aictl/pkg/query"
+	"github.com/mchmarny/aictl/pkg/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	c := Chat{}
+	ctx := context.Background()
+
+	t.Run("Without question", func(t *testing.T) {
+		assert.Error(t, c.query(ctx, " "))
+	})
+
+	t.Run("Without tables", func(t *testing.T) {
+		assert.Error(t, c.query(ctx, "what is the max price?"))
+	})
+
+	t.Run("Add tables", func(t *testing.T) {
+		a, err := table.Read("prices.csv", strings.NewReader("month,price\n2023-01,1.5\n"), ',')
+		assert.NoError(t, err)
+		c.addTables([]*table.Table{a})
+		c.addTables([]*table.Table{a})
+		assert.Len(t, c.tables, 1)
+
+		p := queryPrompt(c.tables, "what is the max price?")
+		assert.Contains(t, p, "Table prices (1 rows):\n- \"month\" date, e.g. \"2023-01\"\n- \"price\" float, e.g. \"1.5\"\n")
+		assert.Contains(t, p, "Question: what is the max price?")
+	})
+}
+
+func TestQueryRedact(t *testing.T) {
+	r, err := redact.New(nil, nil)
+	assert.NoError(t, err)
+	c := Chat{redactor: r}
+
+	a, err := table.Read("users.csv", strings.NewReader("name,email\nJane,jane@example.com\nJoe,joe@example.com\n"), ',')
+	assert.NoError(t, err)
+	c.addTables([]*table.Table{a})
+
+	p := c.redactText("the query", queryPrompt(c.tables, "when did joe@example.com sign up?"))
+	assert.NotContains(t, p, "@example.com")
+	assert.Contains(t, p, "- \"email\" string, e.g. \"[REDACTED_EMAIL_1]\"\n")
+	assert.Contains(t, p, "Question: when did [REDACTED_EMAIL_2] sign up?")
+
+	// the query runs with the original values
+	sql := c.restore(`SELECT "name" FROM users WHERE "email" = '[REDACTED_EMAIL_2]'`)
+	res, err := query.Run(sql, c.tables)
+	assert.NoError(t, err)
+	result := res.Markdown(queryRowsMax)
+	assert.Contains(t, result, "Joe")
+
+	p = c.redactText("the query result", answerPrompt("when did joe@example.com sign up?", sql, result))
+	assert.NotContains(t, p, "@example.com")
+	assert.Contains(t, p, "Query: SELECT \"name\" FROM users WHERE \"email\" = '[REDACTED_EMAIL_2]'")
+}
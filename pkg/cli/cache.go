@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/pkg/errors"
+)
+
+const cacheCommand = "cache"
+
+// runCache runs the cache command (ls or purge [url-prefix]) with the
+// cache c, writing its output to w.
+func runCache(c *url.Cache, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("cache command not set, use: ls or purge [url-prefix]")
+	}
+
+	switch args[0] {
+	case "ls":
+		list, err := c.List()
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", url.Redact(e.URL), formatSize(e.Size), e.Fetched.Local().Format(time.DateTime), e.ContentType)
+		}
+		fmt.Fprintf(w, "%d cached URLs in %s\n", len(list), c.Dir)
+		return nil
+	case "purge":
+		if len(args) > 2 {
+			return errors.New("too many arguments, use: purge [url-prefix]")
+		}
+		prefix := ""
+		if len(args) == 2 {
+			prefix = args[1]
+		}
+		n, err := c.Purge(prefix)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed %d cached URLs.\n", n)
+		return nil
+	}
+	return errors.Errorf("unknown cache command: %s, use: ls or purge [url-prefix]", args[0])
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%dB", n)
+}
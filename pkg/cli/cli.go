@@ -5,17 +5,25 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/mchmarny/aictl/pkg/chat"
 	"github.com/mchmarny/aictl/pkg/chat/gemini"
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/pkg/errors"
 )
 
+const indexCommand = "index"
+
 var (
 	chatter chat.Chat = &gemini.Chat{}
 
+	// errors are written to stderr, so they don't mix with the responses
+	stderr io.Writer = os.Stderr
+
 	// set at build time
 	version = "v0.0.1-default"
 	commit  = "not-set"
@@ -23,21 +31,51 @@ var (
 )
 
 func Start() {
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run() error {
 	ctx := context.Background()
 	defer chatter.Close(ctx)
 
 	// flags
 	info := flag.Bool("info", false, "Show version info.")
 	if err := chatter.Init(ctx); err != nil {
-		fmt.Printf("error initializing chat: %s", err.Error())
-		return
+		fmt.Fprintf(stderr, "error initializing chat: %s\n", err.Error())
+		return err
 	}
 	flag.Parse()
 
 	// info
 	if *info {
 		fmt.Printf("aictl (version: %s, commit: %s, built: %s)\n", version, commit, date)
-		return
+		return nil
+	}
+
+	// commands
+	if args := flag.Args(); len(args) > 0 {
+		var err error
+		switch args[0] {
+		case cacheCommand:
+			var c *url.Cache
+			if c, err = url.DefaultCache(); err == nil {
+				err = runCache(c, args[1:], os.Stdout)
+			}
+		case indexCommand:
+			if ix, ok := chatter.(chat.Indexer); ok {
+				err = ix.Index(ctx, args[1:], os.Stdout)
+			} else {
+				err = errors.New("index not supported")
+			}
+		default:
+			err = errors.Errorf("unknown command: %s", args[0])
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error running %s command: %s\n", args[0], err.Error())
+		}
+		return err
 	}
 
 	// interruptions (e.g. ctrl+c)
@@ -45,13 +83,19 @@ func Start() {
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	// prompt
+	result := make(chan error, 1)
 	go func() {
-		if err := chatter.Start(ctx, bufio.NewScanner(os.Stdin)); err != nil {
-			fmt.Printf("error starting chat: %s", err.Error())
-			return
-		}
+		result <- chatter.Start(ctx, bufio.NewScanner(os.Stdin))
 	}()
 
-	<-done
-	fmt.Println()
+	select {
+	case <-done:
+		fmt.Println()
+		return nil
+	case err := <-result:
+		if err != nil {
+			fmt.Fprintf(stderr, "error running chat: %s\n", err.Error())
+		}
+		return err
+	}
 }
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingChat fails the chat, as the chat does when the response never
+// matches the schema.
+type failingChat struct{}
+
+func (failingChat) Init(context.Context) error {
+	return nil
+}
+
+func (failingChat) Start(context.Context, *bufio.Scanner) error {
+	return errors.New("invalid json response")
+}
+
+func (failingChat) Close(context.Context) error {
+	return nil
+}
+
+func TestRunError(t *testing.T) {
+	oldChatter, oldStderr := chatter, stderr
+	t.Cleanup(func() { chatter, stderr = oldChatter, oldStderr })
+
+	var out bytes.Buffer
+	chatter, stderr = failingChat{}, &out
+
+	// the error is returned so Start exits with non-zero code
+	assert.ErrorContains(t, run(), "invalid json response")
+	assert.Equal(t, "error running chat: invalid json response\n", out.String())
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/mchmarny/aictl/pkg/redact"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const configFile = "aictl/config.yaml"
+
+// Config is the aictl configuration file.
+type Config struct {
+	// Auth are the credentials used for URL: requests to specific hosts.
+	Auth []*url.Auth `yaml:"auth,omitempty"`
+	// Netrc is the path to netrc file, defaults to $NETRC or ~/.netrc.
+	Netrc string `yaml:"netrc,omitempty"`
+	// Cookies is the path to Netscape cookies.txt file.
+	Cookies string `yaml:"cookies,omitempty"`
+
+	// Commands configures CMD: prompts.
+	Commands Commands `yaml:"commands,omitempty"`
+
+	// Redact configures redaction of content sent to the model.
+	Redact Redact `yaml:"redact,omitempty"`
+}
+
+// Commands configures commands run by CMD: prompts.
+type Commands struct {
+	// Allow are the commands which are run without confirmation (e.g.
+	// "kubectl get" allows "kubectl get pods").
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// Redact configures redaction of secrets and personal information in
+// content sent to the model.
+type Redact struct {
+	// Rules are redacted in addition to the built-in detectors.
+	Rules []*redact.Rule `yaml:"rules,omitempty"`
+	// Disable are the names of built-in detectors which aren't used (e.g.
+	// ip).
+	Disable []string `yaml:"disable,omitempty"`
+}
+
+// DefaultPath returns the path of the configuration file in the user
+// config directory (e.g. ~/.config/aictl/config.yaml on Linux).
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, configFile)
+}
+
+// Load reads the configuration file at path, or the default one when path
+// is empty. The default file is optional, empty configuration is returned
+// when it doesn't exist.
+func Load(path string) (*Config, error) {
+	required := path != ""
+	if path == "" {
+		path = DefaultPath()
+	}
+
+	c := &Config{}
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return c, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading config file: %s", path)
+	}
+
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrapf(err, "error parsing config file: %s", path)
+	}
+	c.Netrc = expand(c.Netrc)
+	c.Cookies = expand(c.Cookies)
+	return c, nil
+}
+
+// Credentials returns the credentials for URL: requests.
+func (c *Config) Credentials() (*url.Credentials, error) {
+	return url.NewCredentials(c.Auth, c.Netrc, c.Cookies)
+}
+
+// Redactor returns the redactor of content sent to the model.
+func (c *Config) Redactor() (*redact.Redactor, error) {
+	return redact.New(c.Redact.Rules, c.Redact.Disable)
+}
+
+// expand replaces leading ~ in path with the home directory.
+func expand(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+auth:
+  - host: wiki.example.com
+    token_command: pass show wiki
+    headers:
+      X-Team: web
+netrc: ~/.netrc-work
+redact:
+  rules:
+    - name: employee-id
+      pattern: EMP-\d{6}
+  disable: [ip]
+`), 0o600))
+
+	c, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, c.Auth, 1)
+	assert.Equal(t, "wiki.example.com", c.Auth[0].Host)
+	assert.Equal(t, "pass show wiki", c.Auth[0].TokenCommand)
+	assert.Equal(t, "web", c.Auth[0].Headers["X-Team"])
+
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".netrc-work"), c.Netrc)
+
+	r, err := c.Redactor()
+	assert.NoError(t, err)
+	txt, _ := r.Text("EMP-123456 at 10.1.2.3")
+	assert.Equal(t, "[REDACTED_EMPLOYEE_ID_1] at 10.1.2.3", txt)
+
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte("auth: [\n"), 0o600))
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "error parsing config file")
+}
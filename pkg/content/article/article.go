@@ -0,0 +1,330 @@
+// Package article extracts the main content of HTML pages as Markdown,
+// along with the page metadata (title, author, and published date), by
+// scoring the page nodes by the amount of text they contain, similar to
+// the Readability algorithm.
+package article
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// MinLength is the minimum length of extracted content, shorter content
+// usually means the extraction failed to find the main content.
+const MinLength = 250
+
+var (
+	unlikely = regexp.MustCompile(`(?i)banner|breadcrumb|comment|consent|cookie|disqus|footer|gdpr|header|menu|modal|nav|newsletter|pagination|popup|promo|related|share|sidebar|social|sponsor|subscribe|\bads?\b|\bad-`)
+	likely   = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text`)
+	positive = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	negative = regexp.MustCompile(`(?i)banner|comment|footer|footnote|masthead|media|meta|nav|promo|related|share|sidebar|social|sponsor|widget|\bads?\b`)
+	spaces   = regexp.MustCompile(`[ \t\r\n\f]+`)
+)
+
+// removed are the elements which never hold the main content.
+var removed = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Form: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Svg: true,
+	atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true, atom.Template: true,
+	atom.Object: true, atom.Embed: true, atom.Dialog: true, atom.Menu: true,
+}
+
+// Article is the main content of the page.
+type Article struct {
+	Title       string
+	Author      string
+	Published   string
+	Site        string
+	Description string
+
+	// Content is the main content as Markdown.
+	Content string
+}
+
+// Extract returns the main content of the HTML page. Relative links are
+// resolved against pageURL. Returns error when the page has no content
+// at least MinLength long.
+func Extract(page []byte, pageURL string) (*Article, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing HTML")
+	}
+
+	a := &Article{}
+	meta(doc, a)
+
+	base, _ := url.Parse(pageURL)
+	if b := find(doc, atom.Base); b != nil {
+		if href, err := url.Parse(attr(b, "href")); err == nil && base != nil {
+			base = base.ResolveReference(href)
+		}
+	}
+
+	clean(doc)
+
+	nodes := mainContent(doc)
+	if len(nodes) == 0 {
+		return nil, errors.New("main content not found")
+	}
+
+	c := &converter{base: base}
+	var parts []string
+	for _, n := range nodes {
+		if s := c.block(n); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	a.Content = strings.Join(parts, "\n\n")
+
+	// drop the title repeated as the first heading
+	if a.Title != "" {
+		first, rest, _ := strings.Cut(a.Content, "\n")
+		if strings.EqualFold(strings.TrimLeft(first, "# "), a.Title) {
+			a.Content = strings.TrimSpace(rest)
+		}
+	}
+
+	if len(a.Content) < MinLength {
+		return nil, errors.Errorf("main content too short (%d characters)", len(a.Content))
+	}
+
+	return a, nil
+}
+
+// String returns the metadata followed by the content.
+func (a *Article) String() string {
+	var sb strings.Builder
+	for _, m := range []struct{ name, value string }{
+		{"Title", a.Title},
+		{"Author", a.Author},
+		{"Published", a.Published},
+		{"Site", a.Site},
+		{"Description", a.Description},
+	} {
+		if m.value != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", m.name, m.value)
+		}
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(a.Content)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// clean removes the elements which are unlikely to be part of the main
+// content: scripts, navigation, forms, and elements with class or id
+// suggesting banners, menus, comments, etc.
+func clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.CommentNode:
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && (removed[c.DataAtom] || hidden(c) || isUnlikely(c)):
+			n.RemoveChild(c)
+		default:
+			clean(c)
+		}
+		c = next
+	}
+}
+
+func hidden(n *html.Node) bool {
+	if _, ok := attrOK(n, "hidden"); ok {
+		return true
+	}
+	if attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+func isUnlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main, atom.Table, atom.Tbody, atom.Thead,
+		atom.Tr, atom.Td, atom.Th, atom.Pre, atom.Code, atom.A:
+		return false
+	}
+	id := attr(n, "class") + " " + attr(n, "id") + " " + attr(n, "role")
+	return unlikely.MatchString(id) && !likely.MatchString(id)
+}
+
+// mainContent returns the node with the highest score along with the
+// siblings which are likely part of the same content.
+func mainContent(doc *html.Node) []*html.Node {
+	scores := map[*html.Node]float64{}
+	add := func(n *html.Node, s float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+		}
+		scores[n] += s
+	}
+
+	walk(doc, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return
+		}
+		txt := strings.TrimSpace(text(n))
+		if len(txt) < 25 {
+			return
+		}
+		s := 1 + float64(strings.Count(txt, ",")) + math.Min(float64(len(txt))/100, 3)
+		add(n.Parent, s)
+		if n.Parent != nil {
+			add(n.Parent.Parent, s/2)
+		}
+	})
+
+	var top *html.Node
+	best := 0.0
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		scores[n] = s
+		if top == nil || s > best || (s == best && depth(n) > depth(top)) {
+			top, best = n, s
+		}
+	}
+
+	if top == nil {
+		if b := find(doc, atom.Body); b != nil {
+			return []*html.Node{b}
+		}
+		return nil
+	}
+
+	if top.Parent == nil || top.DataAtom == atom.Body {
+		return []*html.Node{top}
+	}
+
+	// include siblings with similar score or paragraphs with little links
+	threshold := math.Max(10, best*0.2)
+	var nodes []*html.Node
+	for s := top.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		include := s == top || scores[s] >= threshold
+		if !include && s.DataAtom == atom.P {
+			txt := strings.TrimSpace(text(s))
+			include = len(txt) > 80 && linkDensity(s) < 0.25
+		}
+		if include {
+			nodes = append(nodes, s)
+		}
+	}
+	return nodes
+}
+
+func initialScore(n *html.Node) float64 {
+	var s float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		s = 10
+	case atom.Div:
+		s = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		s = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		s = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		s = -5
+	}
+
+	for _, v := range []string{attr(n, "class"), attr(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negative.MatchString(v) {
+			s -= 25
+		}
+		if positive.MatchString(v) {
+			s += 25
+		}
+	}
+	return s
+}
+
+// linkDensity returns the share of node text which is inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(strings.TrimSpace(text(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	walk(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			links += len(strings.TrimSpace(text(c)))
+		}
+	})
+	return math.Min(float64(links)/float64(total), 1)
+}
+
+func depth(n *html.Node) int {
+	d := 0
+	for ; n != nil; n = n.Parent {
+		d++
+	}
+	return d
+}
+
+// walk calls fn for each element node under n (including n).
+func walk(n *html.Node, fn func(*html.Node)) {
+	if n.Type == html.ElementNode {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) {
+		if found == nil && c.DataAtom == a {
+			found = c
+		}
+	})
+	return found
+}
+
+// text returns the text content of the node.
+func text(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(text(c))
+	}
+	return sb.String()
+}
+
+func attr(n *html.Node, name string) string {
+	v, _ := attrOK(n, name)
+	return v
+}
+
+func attrOK(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package article
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const page = `<!DOCTYPE html>
+<html><head>
+<title>Go Concurrency Patterns | Example Blog</title>
+<meta property="og:title" content="Go Concurrency Patterns">
+<meta property="og:site_name" content="Example Blog">
+<meta name="description" content="Pipelines and cancellation in Go.">
+<script type="application/ld+json">{"@context":"https://schema.org","@type":"BlogPosting","headline":"Go Concurrency Patterns","author":[{"@type":"Person","name":"Jane Doe"}],"datePublished":"2024-03-01T10:00:00Z"}</script>
+<script>var tracking = "ignored";</script>
+</head><body>
+<header class="site-header"><a href="/">Home</a> <a href="/about">About</a></header>
+<nav><ul><li><a href="/posts">Posts</a></li><li><a href="/tags">Tags</a></li></ul></nav>
+<div id="cookie-banner">We use cookies to improve your experience, accept all cookies to continue.</div>
+<div class="layout">
+<div class="sidebar"><p>Subscribe to our newsletter, we send great emails, every week, promise.</p></div>
+<article class="post">
+<h1>Go Concurrency Patterns</h1>
+<p>Go's concurrency primitives make it easy to construct streaming data pipelines, that make efficient use of I/O and multiple CPUs.</p>
+<h2>What is a pipeline?</h2>
+<p>There's no formal definition of a pipeline in Go, it's just one of many kinds of <em>concurrent programs</em>. Informally, a pipeline is a series of <strong>stages</strong> connected by channels, see <a href="/docs/channels">channels</a>.</p>
+<pre><code class="language-go">func gen(nums ...int) &lt;-chan int {
+    out := make(chan int)
+    return out
+}</code></pre>
+<ul><li>Receive values from upstream via <code>inbound</code> channels</li><li>Send values downstream</li></ul>
+<table><tr><th>Stage</th><th>Role</th></tr><tr><td>gen</td><td>source</td></tr><tr><td>sq</td><td>transform</td></tr></table>
+<blockquote><p>Don't communicate by sharing memory, share memory by communicating.</p></blockquote>
+</article>
+</div>
+<footer><p>Copyright 2024, Example Blog, all rights reserved, no part may be reproduced.</p></footer>
+</body></html>`
+
+func TestExtract(t *testing.T) {
+	a, err := Extract([]byte(page), "https://blog.example.com/posts/pipelines")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Go Concurrency Patterns", a.Title)
+	assert.Equal(t, "Jane Doe", a.Author)
+	assert.Equal(t, "2024-03-01T10:00:00Z", a.Published)
+	assert.Equal(t, "Example Blog", a.Site)
+	assert.Equal(t, "Pipelines and cancellation in Go.", a.Description)
+
+	want := "Go's concurrency primitives make it easy to construct streaming data pipelines, that make efficient use of I/O and multiple CPUs.\n\n" +
+		"## What is a pipeline?\n\n" +
+		"There's no formal definition of a pipeline in Go, it's just one of many kinds of _concurrent programs_. Informally, a pipeline is a series of **stages** connected by channels, see [channels](https://blog.example.com/docs/channels).\n\n" +
+		"```go\nfunc gen(nums ...int) <-chan int {\n    out := make(chan int)\n    return out\n}\n```\n\n" +
+		"- Receive values from upstream via `inbound` channels\n- Send values downstream\n\n" +
+		"| Stage | Role |\n| --- | --- |\n| gen | source |\n| sq | transform |\n\n" +
+		"> Don't communicate by sharing memory, share memory by communicating."
+	assert.Equal(t, want, a.Content)
+
+	for _, s := range []string{"cookies", "newsletter", "Copyright", "tracking", "Posts"} {
+		assert.NotContains(t, a.Content, s)
+	}
+
+	assert.True(t, strings.HasPrefix(a.String(), "Title: Go Concurrency Patterns\nAuthor: Jane Doe\nPublished: 2024-03-01T10:00:00Z\n"))
+}
+
+func TestExtractShort(t *testing.T) {
+	_, err := Extract([]byte(`<html><body><nav><a href="/">Home</a></nav><p>Too short.</p></body></html>`), "")
+	assert.Error(t, err)
+}
+
+func TestMeta(t *testing.T) {
+	doc := `<html><head><title>Page</title><meta name="author" content="John Smith"></head>
+<body><time datetime="2023-05-06">May 6</time><p>` + strings.Repeat("Some text, with commas. ", 20) + `</p></body></html>`
+	a, err := Extract([]byte(doc), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Page", a.Title)
+	assert.Equal(t, "John Smith", a.Author)
+	assert.Equal(t, "2023-05-06", a.Published)
+}
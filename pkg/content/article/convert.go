@@ -0,0 +1,280 @@
+package article
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// converter renders HTML nodes as Markdown.
+type converter struct {
+	base *url.URL
+}
+
+var blocks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Dd: true, atom.Details: true,
+	atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Hr: true, atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Summary: true, atom.Table: true, atom.Ul: true, atom.Body: true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blocks[n.DataAtom]
+}
+
+// block renders the node as Markdown block.
+func (c *converter) block(n *html.Node) string {
+	if !isBlock(n) {
+		return paragraph(c.inline(n))
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		txt := strings.ReplaceAll(paragraph(c.children(n)), "\n", " ")
+		if txt == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + txt
+	case atom.P, atom.Dt, atom.Dd, atom.Figcaption, atom.Summary:
+		return paragraph(c.children(n))
+	case atom.Pre:
+		return c.code(n)
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Blockquote:
+		body := strings.Join(c.blocks(n), "\n\n")
+		if body == "" {
+			return ""
+		}
+		return "> " + strings.ReplaceAll(body, "\n", "\n> ")
+	case atom.Table:
+		return c.table(n)
+	case atom.Hr:
+		return "---"
+	}
+	return strings.Join(c.blocks(n), "\n\n")
+}
+
+// blocks renders the children of n as blocks, consecutive inline nodes
+// form single paragraph.
+func (c *converter) blocks(n *html.Node) []string {
+	var out []string
+	var para strings.Builder
+	flush := func() {
+		if p := paragraph(para.String()); p != "" {
+			out = append(out, p)
+		}
+		para.Reset()
+	}
+
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if isBlock(ch) {
+			flush()
+			if b := c.block(ch); b != "" {
+				out = append(out, b)
+			}
+			continue
+		}
+		para.WriteString(c.inline(ch))
+	}
+	flush()
+	return out
+}
+
+func (c *converter) children(n *html.Node) string {
+	var sb strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		sb.WriteString(c.inline(ch))
+	}
+	return sb.String()
+}
+
+// inline renders the node as inline Markdown. Block elements nested in
+// inline ones are rendered as their text.
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaces.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		txt := strings.TrimSpace(spaces.ReplaceAllString(text(n), " "))
+		if txt == "" {
+			return ""
+		}
+		return "`" + txt + "`"
+	case atom.Strong, atom.B:
+		return wrap(c.children(n), "**")
+	case atom.Em, atom.I:
+		return wrap(c.children(n), "_")
+	case atom.Del, atom.S, atom.Strike:
+		return wrap(c.children(n), "~~")
+	case atom.A:
+		txt := c.children(n)
+		href := c.resolve(attr(n, "href"))
+		if strings.TrimSpace(txt) == "" || href == "" {
+			return txt
+		}
+		lead, trail := edges(txt)
+		return lead + "[" + strings.TrimSpace(txt) + "](" + href + ")" + trail
+	case atom.Img:
+		alt := strings.TrimSpace(attr(n, "alt"))
+		src := c.resolve(attr(n, "src"))
+		if alt == "" || src == "" {
+			return ""
+		}
+		return "![" + alt + "](" + src + ")"
+	}
+
+	if isBlock(n) {
+		return " " + c.block(n) + " "
+	}
+	return c.children(n)
+}
+
+// code renders preformatted block as fenced code, using the language
+// from the class of pre or code element (e.g. language-go).
+func (c *converter) code(n *html.Node) string {
+	lang := language(n)
+	if code := find(n, atom.Code); code != nil && lang == "" {
+		lang = language(code)
+	}
+	body := strings.Trim(text(n), "\n")
+	if strings.TrimSpace(body) == "" {
+		return ""
+	}
+	return "```" + lang + "\n" + body + "\n```"
+}
+
+func language(n *html.Node) string {
+	for _, cls := range strings.Fields(attr(n, "class")) {
+		for _, p := range []string{"language-", "lang-", "highlight-source-"} {
+			if strings.HasPrefix(cls, p) {
+				return strings.TrimPrefix(cls, p)
+			}
+		}
+	}
+	return ""
+}
+
+// list renders list items with nested content indented under the marker.
+func (c *converter) list(n *html.Node) string {
+	var items []string
+	i := 0
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		i++
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(i) + ". "
+		}
+		body := strings.Join(c.blocks(li), "\n")
+		if body == "" {
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders table as Markdown table using the first row as header.
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	walk(n, func(tr *html.Node) {
+		if tr.DataAtom != atom.Tr {
+			return
+		}
+		var cells []string
+		for td := tr.FirstChild; td != nil; td = td.NextSibling {
+			if td.DataAtom == atom.Td || td.DataAtom == atom.Th {
+				txt := strings.ReplaceAll(paragraph(c.children(td)), "\n", " ")
+				cells = append(cells, strings.ReplaceAll(txt, "|", "\\|"))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, r := range rows {
+		if len(r) > width {
+			width = len(r)
+		}
+	}
+
+	var sb strings.Builder
+	for i, r := range rows {
+		for len(r) < width {
+			r = append(r, "")
+		}
+		sb.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (c *converter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// paragraph trims the lines of inline text and drops the empty ones.
+func paragraph(s string) string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap surrounds the trimmed text with the mark, keeping the outer spaces.
+func wrap(s, mark string) string {
+	if strings.TrimSpace(s) == "" {
+		return s
+	}
+	lead, trail := edges(s)
+	return lead + mark + strings.TrimSpace(s) + mark + trail
+}
+
+func edges(s string) (string, string) {
+	var lead, trail string
+	if strings.HasPrefix(s, " ") {
+		lead = " "
+	}
+	if strings.HasSuffix(s, " ") {
+		trail = " "
+	}
+	return lead, trail
+}
@@ -0,0 +1,133 @@
+package article
+
+import (
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// meta fills the article metadata from meta tags, JSON-LD, and the
+// document title. The first found value wins.
+func meta(doc *html.Node, a *Article) {
+	set := func(field *string, v string) {
+		v = strings.TrimSpace(spaces.ReplaceAllString(v, " "))
+		if *field == "" && v != "" {
+			*field = v
+		}
+	}
+
+	walk(doc, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.Meta:
+			key := strings.ToLower(attr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(n, "name"))
+			}
+			if key == "" {
+				key = strings.ToLower(attr(n, "itemprop"))
+			}
+			v := attr(n, "content")
+			switch key {
+			case "og:title", "twitter:title":
+				set(&a.Title, v)
+			case "author", "article:author", "byl", "sailthru.author", "parsely-author", "dc.creator":
+				// article:author is often a profile URL
+				if !strings.HasPrefix(v, "http") {
+					set(&a.Author, v)
+				}
+			case "article:published_time", "datepublished", "date", "pubdate", "publish-date",
+				"publish_date", "dc.date", "dc.date.issued", "sailthru.date", "parsely-pub-date":
+				set(&a.Published, v)
+			case "og:site_name", "application-name":
+				set(&a.Site, v)
+			case "description", "og:description", "twitter:description":
+				set(&a.Description, v)
+			}
+		case atom.Script:
+			if strings.Contains(attr(n, "type"), "ld+json") {
+				linkedData(text(n), a, set)
+			}
+		case atom.A:
+			if strings.Contains(attr(n, "rel"), "author") {
+				set(&a.Author, text(n))
+			}
+		case atom.Time:
+			if _, ok := attrOK(n, "pubdate"); ok || strings.Contains(attr(n, "itemprop"), "datePublished") {
+				set(&a.Published, attr(n, "datetime"))
+			}
+		}
+	})
+
+	if t := find(doc, atom.Title); t != nil {
+		set(&a.Title, text(t))
+	}
+	if h := find(doc, atom.H1); h != nil {
+		set(&a.Title, text(h))
+	}
+	if t := find(doc, atom.Time); t != nil {
+		set(&a.Published, attr(t, "datetime"))
+	}
+}
+
+// linkedData reads the metadata from JSON-LD (schema.org) article.
+func linkedData(s string, a *Article, set func(*string, string)) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return
+	}
+
+	var visit func(v interface{})
+	visit = func(v interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, x := range v {
+				visit(x)
+			}
+		case map[string]interface{}:
+			if g, ok := v["@graph"]; ok {
+				visit(g)
+			}
+			if _, ok := v["datePublished"]; !ok {
+				if _, ok := v["headline"]; !ok {
+					return
+				}
+			}
+			if s, ok := v["headline"].(string); ok {
+				set(&a.Title, s)
+			}
+			set(&a.Author, name(v["author"]))
+			if s, ok := v["datePublished"].(string); ok {
+				set(&a.Published, s)
+			}
+			set(&a.Site, name(v["publisher"]))
+			if s, ok := v["description"].(string); ok {
+				set(&a.Description, s)
+			}
+		}
+	}
+	visit(v)
+}
+
+// name returns the name of JSON-LD person or organization, which can be
+// a string, an object, or a list of them.
+func name(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if s, ok := v["name"].(string); ok {
+			return s
+		}
+	case []interface{}:
+		var names []string
+		for _, x := range v {
+			if s := name(x); s != "" {
+				names = append(names, s)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package content
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxImageSize is the maximum size of image which can be attached.
+	MaxImageSize = 4 * 1024 * 1024
+)
+
+// Part is a piece of loaded content, either Text or *Blob.
+type Part interface {
+	part()
+}
+
+// Text is textual content.
+type Text string
+
+func (Text) part() {}
+
+// Blob is binary content (e.g. image) with its MIME type.
+type Blob struct {
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+func (*Blob) part() {}
+
+var imageSignatures = []struct {
+	mimeType string
+	match    func(b []byte) bool
+}{
+	{"image/png", func(b []byte) bool { return bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) }},
+	{"image/jpeg", func(b []byte) bool { return bytes.HasPrefix(b, []byte("\xff\xd8\xff")) }},
+	{"image/webp", func(b []byte) bool {
+		return len(b) >= 12 && bytes.Equal(b[:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP"))
+	}},
+}
+
+// ImageType returns the MIME type of image in b, or empty string when b
+// is not one of the supported image formats (PNG, JPEG, or WEBP).
+func ImageType(b []byte) string {
+	for _, s := range imageSignatures {
+		if s.match(b) {
+			return s.mimeType
+		}
+	}
+	return ""
+}
+
+// IsImageType reports whether the MIME type is one of the supported
+// image formats.
+func IsImageType(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	for _, s := range imageSignatures {
+		if s.mimeType == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinText returns the text of all parts, or error when any of the parts
+// is not text.
+func JoinText(parts []Part) (string, error) {
+	var sb strings.Builder
+	for _, p := range parts {
+		t, ok := p.(Text)
+		if !ok {
+			return "", errors.New("content includes non-text parts")
+		}
+		sb.WriteString(string(t))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,26 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageType(t *testing.T) {
+	assert.Equal(t, "image/png", ImageType([]byte("\x89PNG\r\n\x1a\n....")))
+	assert.Equal(t, "image/jpeg", ImageType([]byte("\xff\xd8\xff\xe0")))
+	assert.Equal(t, "image/webp", ImageType([]byte("RIFF\x00\x00\x00\x00WEBPVP8 ")))
+	assert.Equal(t, "", ImageType([]byte("GIF89a")))
+
+	assert.True(t, IsImageType("image/PNG; charset=binary"))
+	assert.False(t, IsImageType("text/html"))
+}
+
+func TestJoinText(t *testing.T) {
+	s, err := JoinText([]Part{Text("a"), Text("b")})
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", s)
+
+	_, err = JoinText([]Part{Text("a"), &Blob{}})
+	assert.Error(t, err)
+}
@@ -2,33 +2,338 @@ package file
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/content/office"
+	"github.com/mchmarny/aictl/pkg/content/pdf"
+	"github.com/mchmarny/aictl/pkg/content/structured"
+	"github.com/mchmarny/aictl/pkg/content/table"
 	"github.com/pkg/errors"
 )
 
+const (
+	// MaxSizeDefault is the default limit of total size of selected files.
+	MaxSizeDefault = 100 * 1024
+
+	sniffLen = 8000
+)
+
+// Skipped is a file which was matched but not selected.
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// Selection is a set of files selected for loading.
+type Selection struct {
+	Files   []string
+	Skipped []*Skipped
+	Size    int64
+
+	// Sample is the number of rows included from tables (CSV or TSV)
+	// which don't fit into the size limit.
+	Sample int
+
+	// Items is the number of elements included from arrays in JSON and
+	// YAML documents, the rest is summarized.
+	Items int
+
+	// Tables loaded by Parts, so they can be queried locally.
+	Tables []*table.Table
+
+	// pages selected in PDF document (e.g. report.pdf#pages=3-7)
+	pages string
+	// sheet selected in XLSX workbook (e.g. budget.xlsx#sheet=Q3)
+	sheet string
+	// subtree selected in JSON or YAML document (e.g. state.json#.items[].metadata)
+	selector string
+	maxSize  int64
+}
+
+// GetContent returns the description followed by the content of files
+// selected by path (see Select) using the default size limit.
 func GetContent(desc, path string) (string, error) {
+	s, err := Select(path, MaxSizeDefault)
+	if err != nil {
+		return "", err
+	}
+	return s.Content(desc)
+}
+
+// Select returns files matching path, which can be a file, a directory,
+// or a glob pattern (e.g. pkg/**/*.go). Directories and patterns skip the
+// files ignored by .gitignore (and the .gitignore files). Binary files and
+// files which would exceed the total size limit are skipped, the limit
+// doesn't apply to single file. PDF documents are loaded as text,
+// optionally limited to selected pages (e.g. report.pdf#pages=3-7). Sheets
+// of XLSX workbooks are loaded as tables, optionally only the selected one
+// (e.g. budget.xlsx#sheet=Q3). JSON and YAML documents can be limited to
+// selected subtree (e.g. state.json#.items[].metadata).
+func Select(path string, maxSize int64) (*Selection, error) {
+	path = filepath.ToSlash(strings.TrimSpace(path))
+	if path == "" {
+		return nil, errors.New("file path not set")
+	}
+
+	s := &Selection{maxSize: maxSize}
+
+	if p, pages := pdf.SplitPath(path); pages != "" {
+		path = p
+		s.pages = pages
+	}
+	if p, sheet := office.SplitPath(path); sheet != "" {
+		path = p
+		s.sheet = sheet
+	}
+	if p, sel := structured.SplitPath(path); sel != "" {
+		path = p
+		s.selector = sel
+	}
+
+	if !hasMeta(path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error opening file: %s", path)
+		}
+		if !info.IsDir() {
+			// the size limit applies only to files selected by
+			// directory or pattern, single file is always loaded
+			s.add(path, info.Size(), 0)
+			return s, nil
+		}
+	}
+
+	base, pattern := splitPattern(path)
+	ig := newIgnorer(base)
+
+	var matched []string
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != base && ig.ignored(p, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		if pattern != "" {
+			rel, err := filepath.Rel(base, p)
+			if err != nil || !match(pattern, filepath.ToSlash(rel)) {
+				return nil
+			}
+		}
+		matched = append(matched, p)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading files: %s", path)
+	}
+
+	sort.Strings(matched)
+	for _, p := range matched {
+		info, err := os.Stat(p)
+		if err != nil {
+			s.Skipped = append(s.Skipped, &Skipped{Path: p, Reason: err.Error()})
+			continue
+		}
+		s.add(p, info.Size(), maxSize)
+	}
+
+	if len(s.Files) == 0 && len(s.Skipped) == 0 {
+		return nil, errors.Errorf("no files found: %s", path)
+	}
+
+	return s, nil
+}
+
+func (s *Selection) add(path string, size, maxSize int64) {
+	head := sniff(path)
+	switch {
+	case content.ImageType(head) != "":
+		// images are attached as is, so they don't count towards the text limit
+		if size > content.MaxImageSize {
+			s.Skipped = append(s.Skipped, &Skipped{Path: path, Reason: "size limit"})
+			return
+		}
+		s.Files = append(s.Files, path)
+	case !pdf.IsPDF(path) && !office.IsOffice(path) && bytes.IndexByte(head, 0) >= 0:
+		s.Skipped = append(s.Skipped, &Skipped{Path: path, Reason: "binary"})
+	case table.IsTable(path) || office.Type(path) == office.XLSX:
+		// tables over the limit are sampled, so they are always included
+		s.Files = append(s.Files, path)
+	case structured.IsStructured(path) && s.selector != "":
+		// only the selected subtree is loaded
+		s.Files = append(s.Files, path)
+	case maxSize > 0 && s.Size+size > maxSize:
+		s.Skipped = append(s.Skipped, &Skipped{Path: path, Reason: "size limit"})
+	default:
+		s.Files = append(s.Files, path)
+		s.Size += size
+	}
+}
+
+// Content returns the description followed by the content of selected
+// files. When more than one file is selected, each one is preceded by
+// a header with its path. Returns error when selection includes images,
+// use Parts instead.
+func (s *Selection) Content(desc string) (string, error) {
+	parts, err := s.Parts(desc)
+	if err != nil {
+		return "", err
+	}
+	return content.JoinText(parts)
+}
+
+// Parts returns the description followed by the content of selected files
+// as text parts, and images as blob parts.
+func (s *Selection) Parts(desc string) ([]content.Part, error) {
+	if len(s.Files) == 0 {
+		return nil, errors.New("no files to load")
+	}
+
+	var parts []content.Part
+	var sb strings.Builder
+	sb.WriteString(desc)
+	sb.WriteString("\n")
+
+	for _, p := range s.Files {
+		if len(s.Files) > 1 {
+			fmt.Fprintf(&sb, "\n--- %s ---\n", p)
+		}
+		if mimeType := content.ImageType(sniff(p)); mimeType != "" {
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading file: %s", p)
+			}
+			parts = append(parts, content.Text(sb.String()), &content.Blob{Name: p, MIMEType: mimeType, Data: b})
+			sb.Reset()
+			continue
+		}
+		if table.IsTable(p) || office.Type(p) == office.XLSX {
+			tables, err := s.loadTables(p)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range tables {
+				txt, err := t.Content(s.Sample, s.budget())
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(txt)
+				// the next tables get only what's left of the limit
+				s.Size += int64(len(txt))
+			}
+			s.Tables = append(s.Tables, tables...)
+			continue
+		}
+		if office.IsOffice(p) {
+			txt, err := office.Text(p)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(txt)
+			continue
+		}
+		if structured.IsStructured(p) {
+			items := s.Items
+			if items <= 0 {
+				items = structured.ItemsDefault
+			}
+			txt, err := structured.Load(p, s.selector, items)
+			if err != nil {
+				return nil, err
+			}
+			if s.selector != "" {
+				fmt.Fprintf(&sb, "Selected %s:\n", s.selector)
+			}
+			sb.WriteString(txt)
+			sb.WriteString("\n")
+			continue
+		}
+		if pdf.IsPDF(p) {
+			txt, err := pdf.Text(p, s.pages)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(txt)
+			continue
+		}
+		if err := readFile(p, &sb); err != nil {
+			return nil, err
+		}
+	}
+
+	if sb.Len() > 0 {
+		parts = append(parts, content.Text(sb.String()))
+	}
+
+	return parts, nil
+}
+
+// loadTables returns the table in CSV file, or the selected sheets of
+// XLSX workbook.
+func (s *Selection) loadTables(path string) ([]*table.Table, error) {
+	if office.Type(path) == office.XLSX {
+		return office.Sheets(path, s.sheet)
+	}
+	t, err := table.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return []*table.Table{t}, nil
+}
+
+// budget returns the remaining size limit, or 0 when there is no limit.
+func (s *Selection) budget() int {
+	if s.maxSize <= 0 {
+		return 0
+	}
+	if left := s.maxSize - s.Size; left > 0 {
+		return int(left)
+	}
+	return 1
+}
+
+func readFile(path string, sb *strings.Builder) error {
 	f, err := os.Open(path)
 	if err != nil {
-		return "", errors.Wrapf(err, "error opening file: %s", path)
+		return errors.Wrapf(err, "error opening file: %s", path)
 	}
 	defer f.Close()
 
-	var content strings.Builder
 	scanner := bufio.NewScanner(f)
-
-	content.WriteString(desc)
-	content.WriteString("\n")
-
 	for scanner.Scan() {
-		content.WriteString(scanner.Text())
-		content.WriteString("\n")
+		sb.WriteString(scanner.Text())
+		sb.WriteString("\n")
 	}
 
 	if err := scanner.Err(); err != nil {
-		return "", errors.Wrapf(err, "error scanning file: %s", path)
+		return errors.Wrapf(err, "error scanning file: %s", path)
+	}
+
+	return nil
+}
+
+// sniff returns the first few kilobytes of the file at path.
+func sniff(path string) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
 	}
+	defer f.Close()
 
-	return content.String(), nil
+	b := make([]byte, sniffLen)
+	n, _ := f.Read(b)
+	return b[:n]
 }
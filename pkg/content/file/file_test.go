@@ -1,8 +1,14 @@
 package file
 
 import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/mchmarny/aictl/pkg/content"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,3 +23,230 @@ func TestGetFileContent(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0o700))
+		assert.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+}
+
+func TestSelect(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0o700))
+	writeFiles(t, dir, map[string]string{
+		".gitignore":       "*.log\nbuild/\n!keep.log\n",
+		"main.go":          "package main\n",
+		"pkg/a/a.go":       "package a\n",
+		"pkg/a/a.txt":      "notes\n",
+		"pkg/b/b.go":       "package b\n",
+		"pkg/b/.gitignore": "b.go\n",
+		"pkg/c/c.go":       "package c\n",
+		"app.log":          "log\n",
+		"keep.log":         "keep\n",
+		"build/out.go":     "package out\n",
+		"bin.dat":          "a\x00b",
+		".git/config":      "[core]\n",
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		s, err := Select(dir, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "keep.log"),
+			filepath.Join(dir, "main.go"),
+			filepath.Join(dir, "pkg/a/a.go"),
+			filepath.Join(dir, "pkg/a/a.txt"),
+			filepath.Join(dir, "pkg/c/c.go"),
+		}, s.Files)
+		assert.Equal(t, []*Skipped{{Path: filepath.Join(dir, "bin.dat"), Reason: "binary"}}, s.Skipped)
+	})
+
+	t.Run("Glob", func(t *testing.T) {
+		s, err := Select(filepath.ToSlash(dir)+"/pkg/**/*.go", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "pkg/a/a.go"),
+			filepath.Join(dir, "pkg/c/c.go"),
+		}, s.Files)
+
+		content, err := s.Content("desc")
+		assert.NoError(t, err)
+		assert.Contains(t, content, "--- "+filepath.Join(dir, "pkg/a/a.go")+" ---\npackage a\n")
+	})
+
+	t.Run("Size limit", func(t *testing.T) {
+		s, err := Select(filepath.ToSlash(dir)+"/pkg/**/*.go", 12)
+		assert.NoError(t, err)
+		assert.Len(t, s.Files, 1)
+		assert.Equal(t, "size limit", s.Skipped[0].Reason)
+
+		// single file is loaded regardless of the limit
+		s, err = Select(filepath.Join(dir, "main.go"), 5)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "main.go")}, s.Files)
+		assert.Empty(t, s.Skipped)
+	})
+
+	t.Run("No match", func(t *testing.T) {
+		_, err := Select(filepath.ToSlash(dir)+"/**/*.rs", 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, match("**/*.go", "a.go"))
+	assert.True(t, match("**/*.go", "a/b/c.go"))
+	assert.True(t, match("a/**/c.go", "a/c.go"))
+	assert.False(t, match("*.go", "a/b.go"))
+	assert.False(t, match("a/**/c.go", "b/c.go"))
+}
+
+func TestSelectPDF(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "doc.pdf")
+	assert.NoError(t, os.WriteFile(p, []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n"+
+		"2 0 obj\n<< /Type /Pages /Kids [3 0 R 5 0 R] >>\nendobj\n"+
+		"3 0 obj\n<< /Type /Page /Contents 4 0 R >>\nendobj\n"+
+		"4 0 obj\n<< /Length 26 >>\nstream\nBT (First page text) Tj ET\nendstream\nendobj\n"+
+		"5 0 obj\n<< /Type /Page /Contents 6 0 R >>\nendobj\n"+
+		"6 0 obj\n<< /Length 27 >>\nstream\nBT (Second page text) Tj ET\nendstream\nendobj\n"), 0o600))
+
+	s, err := Select(p+"#pages=2", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{p}, s.Files)
+
+	content, err := s.Content("desc")
+	assert.NoError(t, err)
+	assert.Equal(t, "desc\n--- page 2 ---\nSecond page text\n", content)
+}
+
+func TestSelectImage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.png": "\x89PNG\r\n\x1a\n\x00\x00",
+		"b.txt": "text\n",
+	})
+
+	s, err := Select(dir, 0)
+	assert.NoError(t, err)
+	assert.Len(t, s.Files, 2)
+
+	parts, err := s.Parts("desc")
+	assert.NoError(t, err)
+	assert.Len(t, parts, 3)
+	assert.Equal(t, content.Text("desc\n\n--- "+filepath.Join(dir, "a.png")+" ---\n"), parts[0])
+	assert.Equal(t, &content.Blob{
+		Name:     filepath.Join(dir, "a.png"),
+		MIMEType: "image/png",
+		Data:     []byte("\x89PNG\r\n\x1a\n\x00\x00"),
+	}, parts[1])
+	assert.Equal(t, content.Text("\n--- "+filepath.Join(dir, "b.txt")+" ---\ntext\n"), parts[2])
+
+	_, err = s.Content("desc")
+	assert.Error(t, err)
+}
+
+func TestSelectTable(t *testing.T) {
+	s, err := Select("../../../content/monthly-gas-price.csv", 1024)
+	assert.NoError(t, err)
+	s.Sample = 3
+
+	content, err := s.Content("desc")
+	assert.NoError(t, err)
+	assert.Contains(t, content, "desc\nTable monthly-gas-price.csv (284 rows, 2 columns):\n")
+	assert.Contains(t, content, "Sample data (3 of 284 rows):\n")
+	assert.Len(t, s.Tables, 1)
+}
+
+func TestSelectTables(t *testing.T) {
+	dir := t.TempDir()
+	data := "id,name\n"
+	for i := 0; i < 100; i++ {
+		data += fmt.Sprintf("%d,name %d\n", i, i)
+	}
+	writeFiles(t, dir, map[string]string{"a.csv": data, "b.csv": data})
+
+	// both tables fit into the limit alone, but not together
+	s, err := Select(filepath.Join(dir, "*.csv"), int64(len(data)+512))
+	assert.NoError(t, err)
+	s.Sample = 3
+
+	content, err := s.Content("desc")
+	assert.NoError(t, err)
+	assert.Contains(t, content, "--- "+filepath.Join(dir, "a.csv")+" ---\nTable a.csv (100 rows, 2 columns):\n")
+	assert.Contains(t, content, "99,name 99\n\n--- "+filepath.Join(dir, "b.csv")+" ---\n")
+	assert.Contains(t, content, "Sample data (3 of 100 rows):\n")
+	assert.Len(t, s.Tables, 2)
+}
+
+func TestSelectStructured(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"items": [{"name": "a"}, {"name": "b"}, {"name": "c"}]}`), 0o600))
+
+	s, err := Select(path+"#.items[].name", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{path}, s.Files)
+	s.Items = 2
+
+	content, err := s.Content("desc")
+	assert.NoError(t, err)
+	assert.Equal(t, "desc\nSelected .items[].name:\n[\"a\",\"b\",\"... 1 more of 3 items\"]\n", content)
+}
+
+func TestSelectOffice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.docx")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	w := zip.NewWriter(f)
+	pw, err := w.Create("word/document.xml")
+	assert.NoError(t, err)
+	_, err = pw.Write([]byte(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>Hello</w:t></w:r></w:p></w:body></w:document>`))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, f.Close())
+
+	s, err := Select(path, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, s.Skipped)
+
+	content, err := s.Content("desc")
+	assert.NoError(t, err)
+	assert.Equal(t, "desc\nHello\n", content)
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go":    "package a\n",
+		"b.go":    "package b\n",
+		"bin.dat": "a\x00b",
+	})
+
+	l := &Loader{MaxSize: MaxSizeDefault}
+	assert.Equal(t, Scheme, l.Scheme())
+
+	src, err := l.Open(ctx, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, dir, src.Name())
+	assert.Equal(t, int64(20), src.Size())
+	assert.Equal(t, []string{
+		"Including 2 files (0 KB):",
+		"  " + filepath.Join(dir, "a.go"),
+		"  " + filepath.Join(dir, "b.go"),
+		"Skipping 1 files:",
+		"  " + filepath.Join(dir, "bin.dat") + " (binary)",
+	}, src.Info())
+
+	c, err := src.Load(ctx, "desc")
+	assert.NoError(t, err)
+	txt, err := content.JoinText(c.Parts)
+	assert.NoError(t, err)
+	assert.Contains(t, txt, "package b\n")
+
+	_, err = l.Open(ctx, filepath.Join(dir, "*.dat"))
+	assert.ErrorContains(t, err, "no files to load")
+}
@@ -0,0 +1,144 @@
+package file
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const gitIgnoreFile = ".gitignore"
+
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignorer matches paths against the .gitignore files in the directories
+// from the repository root down to the directory of the matched path.
+type ignorer struct {
+	root  string
+	rules map[string][]*ignoreRule
+}
+
+// newIgnorer creates ignorer for dir, rooted at the enclosing git
+// repository, or dir itself when it's not in a repository.
+func newIgnorer(dir string) *ignorer {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+
+	root := abs
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			root = d
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	return &ignorer{
+		root:  root,
+		rules: map[string][]*ignoreRule{},
+	}
+}
+
+// ignored reports whether the file or directory at p should be skipped.
+func (g *ignorer) ignored(p string, isDir bool) bool {
+	if isDir && filepath.Base(p) == ".git" {
+		return true
+	}
+	// the ignore rules aren't content of the project
+	if !isDir && filepath.Base(p) == gitIgnoreFile {
+		return true
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(g.root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// rules in deeper directories take precedence
+	ignored := false
+	dirs := strings.Split(rel, "/")
+	for i := 0; i < len(dirs); i++ {
+		dir := path.Join(dirs[:i]...)
+		sub := path.Join(dirs[i:]...)
+		for _, r := range g.load(dir) {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.matches(sub) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+func (r *ignoreRule) matches(rel string) bool {
+	if r.anchored {
+		return match(r.pattern, rel)
+	}
+	return match(r.pattern, path.Base(rel))
+}
+
+// load returns the rules from .gitignore in dir relative to the root.
+func (g *ignorer) load(dir string) []*ignoreRule {
+	if rules, ok := g.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []*ignoreRule
+	f, err := os.Open(filepath.Join(g.root, filepath.FromSlash(dir), gitIgnoreFile))
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if r := parseRule(scanner.Text()); r != nil {
+				rules = append(rules, r)
+			}
+		}
+	}
+
+	g.rules[dir] = rules
+	return rules
+}
+
+func parseRule(line string) *ignoreRule {
+	line = strings.TrimRight(line, " \r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	r := &ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	r.pattern = line
+	return r
+}
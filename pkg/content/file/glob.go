@@ -0,0 +1,59 @@
+package file
+
+import (
+	"path"
+	"strings"
+)
+
+// hasMeta reports whether p contains any of the glob special characters.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// splitPattern returns the directory part of the pattern without any glob
+// characters, and the remaining pattern relative to that directory.
+func splitPattern(p string) (string, string) {
+	parts := strings.Split(path.Clean(p), "/")
+	for i, part := range parts {
+		if hasMeta(part) {
+			base := strings.Join(parts[:i], "/")
+			if base == "" {
+				base = "."
+				if strings.HasPrefix(p, "/") {
+					base = "/"
+				}
+			}
+			return base, strings.Join(parts[i:], "/")
+		}
+	}
+	return p, ""
+}
+
+// match reports whether the slash separated path matches the pattern.
+// In addition to the path.Match syntax, "**" matches any number of
+// directories.
+func match(pattern, name string) bool {
+	return matchParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
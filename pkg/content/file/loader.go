@@ -0,0 +1,83 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/pkg/errors"
+)
+
+// Scheme is the prompt prefix of files (e.g. FILE:path).
+const Scheme = "FILE"
+
+// Loader loads files selected by path (see Select).
+type Loader struct {
+	// MaxSize is the limit of total size of selected files.
+	MaxSize int64
+	// Sample and Items are set on the selection (see Selection).
+	Sample int
+	Items  int
+}
+
+func (l *Loader) Scheme() string {
+	return Scheme
+}
+
+func (l *Loader) Open(_ context.Context, location string) (content.Source, error) {
+	sel, err := Select(location, l.MaxSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading file: %s", location)
+	}
+	sel.Sample = l.Sample
+	sel.Items = l.Items
+
+	s := &source{path: location, sel: sel}
+	if len(sel.Files) == 0 {
+		return nil, errors.Errorf("no files to load: %s, skipped %d files", location, len(sel.Skipped))
+	}
+	return s, nil
+}
+
+type source struct {
+	path string
+	sel  *Selection
+}
+
+func (s *source) Name() string {
+	return s.path
+}
+
+func (s *source) Size() int64 {
+	return s.sel.Size
+}
+
+// Info lists the selected and skipped files when more than one file was
+// matched.
+func (s *source) Info() []string {
+	if len(s.sel.Files) < 2 && len(s.sel.Skipped) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Including %d files (%d KB):", len(s.sel.Files), s.sel.Size/1024)}
+	for _, f := range s.sel.Files {
+		lines = append(lines, "  "+f)
+	}
+	if len(s.sel.Skipped) > 0 {
+		lines = append(lines, fmt.Sprintf("Skipping %d files:", len(s.sel.Skipped)))
+		for _, f := range s.sel.Skipped {
+			lines = append(lines, fmt.Sprintf("  %s (%s)", f.Path, f.Reason))
+		}
+	}
+	return lines
+}
+
+func (s *source) Load(_ context.Context, desc string) (*content.Content, error) {
+	parts, err := s.sel.Parts(desc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading file: %s", s.path)
+	}
+	return &content.Content{
+		Parts:  parts,
+		Tables: s.sel.Tables,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	logLimit = "20"
+)
+
+// GetContent returns the description followed by the git content selected
+// by spec from the repository in the current directory. Supported specs:
+//
+//	diff            working tree changes (default)
+//	staged          staged changes
+//	main..HEAD      commits and changes in the range
+//	log <path>      recent commits which changed path
+//	blame <path>    line by line history of path
+//	<commit>        the commit and its changes
+func GetContent(desc, spec string) (string, error) {
+	return getContent(".", desc, spec)
+}
+
+func getContent(dir, desc, spec string) (string, error) {
+	cmds, err := parse(spec)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	content.WriteString(desc)
+	content.WriteString("\n")
+
+	empty := true
+	for _, args := range cmds {
+		out, err := run(dir, args...)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(out) != "" {
+			empty = false
+		}
+		content.WriteString(out)
+	}
+
+	if empty {
+		return "", errors.Errorf("no git content for: %s", spec)
+	}
+
+	return content.String(), nil
+}
+
+// parse returns the git commands for spec.
+func parse(spec string) ([][]string, error) {
+	args := strings.Fields(spec)
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			return nil, errors.Errorf("invalid git argument: %s", a)
+		}
+	}
+
+	if len(args) == 0 {
+		return [][]string{{"diff"}}, nil
+	}
+
+	switch args[0] {
+	case "diff":
+		return [][]string{append([]string{"diff", "--"}, args[1:]...)}, nil
+	case "staged":
+		return [][]string{append([]string{"diff", "--staged", "--"}, args[1:]...)}, nil
+	case "log":
+		if len(args) < 2 {
+			return nil, errors.New("git log requires path")
+		}
+		return [][]string{append([]string{"log", "--max-count=" + logLimit, "--stat", "--"}, args[1:]...)}, nil
+	case "blame":
+		if len(args) != 2 {
+			return nil, errors.New("git blame requires single path")
+		}
+		return [][]string{{"blame", "--", args[1]}}, nil
+	}
+
+	if len(args) > 1 {
+		return nil, errors.Errorf("invalid git spec: %s", spec)
+	}
+
+	if strings.Contains(args[0], "..") {
+		return [][]string{
+			{"log", "--oneline", args[0]},
+			{"diff", args[0]},
+		}, nil
+	}
+
+	return [][]string{{"show", args[0]}}, nil
+}
+
+func run(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "error running git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
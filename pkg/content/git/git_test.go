@@ -0,0 +1,99 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		_, err := run(dir, args...)
+		assert.NoError(t, err)
+	}
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "test")
+	write("a.txt", "one\n")
+	git("add", "a.txt")
+	git("commit", "--quiet", "-m", "first")
+	git("checkout", "--quiet", "-b", "feature")
+	write("a.txt", "one\ntwo\n")
+	git("commit", "--quiet", "-am", "second")
+	write("a.txt", "one\ntwo\nthree\n")
+	write("b.txt", "staged\n")
+	git("add", "b.txt")
+
+	return dir
+}
+
+func TestGetContent(t *testing.T) {
+	dir := newRepo(t)
+
+	tests := []struct {
+		spec     string
+		contains []string
+	}{
+		{"", []string{"+three"}},
+		{"diff a.txt", []string{"+three"}},
+		{"staged", []string{"+staged"}},
+		{"main..HEAD", []string{"second", "+two"}},
+		{"HEAD", []string{"second", "+two"}},
+		{"log a.txt", []string{"first", "second"}},
+		{"blame a.txt", []string{"one", "two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			content, err := getContent(dir, "test", tt.spec)
+			assert.NoError(t, err)
+			assert.Contains(t, content, "test\n")
+			for _, c := range tt.contains {
+				assert.Contains(t, content, c)
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, spec := range []string{"--output=x", "log", "blame", "a b", "not-a-commit"} {
+			_, err := getContent(dir, "test", spec)
+			assert.Error(t, err, spec)
+		}
+	})
+
+	t.Run("No changes", func(t *testing.T) {
+		_, err := getContent(dir, "test", "diff b.txt")
+		assert.Error(t, err)
+	})
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	l := &Loader{Dir: newRepo(t)}
+
+	src, err := l.Open(ctx, "staged")
+	assert.NoError(t, err)
+	assert.Equal(t, "GIT:staged", src.Name())
+
+	c, err := src.Load(ctx, "desc")
+	assert.NoError(t, err)
+	assert.Len(t, c.Parts, 1)
+	assert.Contains(t, c.Parts[0], "+staged")
+
+	_, err = l.Open(ctx, "diff --output=x")
+	assert.Error(t, err)
+}
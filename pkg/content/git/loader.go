@@ -0,0 +1,57 @@
+package git
+
+import (
+	"context"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/pkg/errors"
+)
+
+// Scheme is the prompt prefix of git content (e.g. GIT:staged).
+const Scheme = "GIT"
+
+// Loader loads git content selected by spec (see GetContent) from the
+// repository in Dir, or in the current directory when not set.
+type Loader struct {
+	Dir string
+}
+
+func (l *Loader) Scheme() string {
+	return Scheme
+}
+
+func (l *Loader) Open(_ context.Context, location string) (content.Source, error) {
+	if _, err := parse(location); err != nil {
+		return nil, err
+	}
+	dir := l.Dir
+	if dir == "" {
+		dir = "."
+	}
+	return &source{dir: dir, spec: location}, nil
+}
+
+type source struct {
+	dir  string
+	spec string
+}
+
+func (s *source) Name() string {
+	return Scheme + ":" + s.spec
+}
+
+func (s *source) Size() int64 {
+	return -1
+}
+
+func (s *source) Info() []string {
+	return nil
+}
+
+func (s *source) Load(_ context.Context, desc string) (*content.Content, error) {
+	txt, err := getContent(s.dir, desc, s.spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading git: %s", s.spec)
+	}
+	return &content.Content{Parts: []content.Part{content.Text(txt)}}, nil
+}
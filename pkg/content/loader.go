@@ -0,0 +1,155 @@
+package content
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mchmarny/aictl/pkg/content/table"
+)
+
+// Loader loads content referenced in prompts using its scheme prefix
+// (e.g. FILE:path or URL:url).
+type Loader interface {
+	// Scheme is the prompt prefix without the colon (e.g. FILE).
+	Scheme() string
+	// Open resolves the location (the rest of the prompt) into source
+	// which is ready to be loaded.
+	Open(ctx context.Context, location string) (Source, error)
+}
+
+// ExtensionLoader is loader which handles the locations with one of its
+// extensions (e.g. .ipynb) in place of the general loader of its scheme.
+type ExtensionLoader interface {
+	Loader
+	Extensions() []string
+}
+
+// Source is content resolved by loader.
+type Source interface {
+	// Name is the name of the content (e.g. path or url).
+	Name() string
+	// Info describes what will be loaded (e.g. the selected files).
+	Info() []string
+	// Size is the estimated size of the content in bytes, or -1 when it
+	// isn't known before the content is loaded.
+	Size() int64
+	// Load returns the content preceded by its description.
+	Load(ctx context.Context, desc string) (*Content, error)
+}
+
+// Confirmer is source which has to be confirmed before it's loaded (e.g.
+// command which is run to load its output).
+type Confirmer interface {
+	// Confirm returns the question asked before the source is loaded, or
+	// empty string when it doesn't need to be confirmed.
+	Confirm() string
+}
+
+// Content is the content loaded from source.
+type Content struct {
+	Parts []Part
+	// Tables loaded along with the content, so they can be queried
+	// locally.
+	Tables []*table.Table
+	// Info describes what was loaded (e.g. the crawled pages).
+	Info []string
+}
+
+// Size returns the size of the content parts in bytes.
+func (c *Content) Size() int64 {
+	var n int64
+	for _, p := range c.Parts {
+		switch v := p.(type) {
+		case Text:
+			n += int64(len(v))
+		case *Blob:
+			n += int64(len(v.Data))
+		}
+	}
+	return n
+}
+
+// Registry finds loaders by the scheme prefix of prompts, and by the
+// extension of the location for extension loaders.
+type Registry struct {
+	loaders    map[string]Loader
+	extensions map[string]map[string]Loader
+}
+
+// NewRegistry returns registry with the loaders.
+func NewRegistry(loaders ...Loader) *Registry {
+	r := &Registry{
+		loaders:    map[string]Loader{},
+		extensions: map[string]map[string]Loader{},
+	}
+	for _, l := range loaders {
+		r.Register(l)
+	}
+	return r
+}
+
+// Register adds the loader, replacing the loader previously registered
+// for the same scheme (or the same scheme and extensions).
+func (r *Registry) Register(l Loader) {
+	el, ok := l.(ExtensionLoader)
+	if !ok {
+		r.loaders[l.Scheme()] = l
+		return
+	}
+	m := r.extensions[l.Scheme()]
+	if m == nil {
+		m = map[string]Loader{}
+		r.extensions[l.Scheme()] = m
+	}
+	for _, ext := range el.Extensions() {
+		m[strings.ToLower(ext)] = l
+	}
+}
+
+// Schemes returns the sorted schemes of the registered loaders.
+func (r *Registry) Schemes() []string {
+	var list []string
+	for s := range r.loaders {
+		list = append(list, s)
+	}
+	for s := range r.extensions {
+		if _, ok := r.loaders[s]; !ok {
+			list = append(list, s)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Find returns the loader for prompt starting with scheme prefix (e.g.
+// FILE:report.pdf), and the location following the prefix.
+func (r *Registry) Find(prompt string) (Loader, string, bool) {
+	scheme, location, ok := strings.Cut(prompt, ":")
+	if !ok {
+		return nil, "", false
+	}
+
+	if m := r.extensions[scheme]; m != nil {
+		if l, ok := m[extension(location)]; ok {
+			return l, location, true
+		}
+	}
+	if l, ok := r.loaders[scheme]; ok {
+		return l, location, true
+	}
+	return nil, "", false
+}
+
+// extension returns the extension of the location without its arguments,
+// query, or fragment.
+func extension(location string) string {
+	f := strings.Fields(location)
+	if len(f) == 0 {
+		return ""
+	}
+	p, _, _ := strings.Cut(f[0], "#")
+	p, _, _ = strings.Cut(p, "?")
+	return strings.ToLower(path.Ext(p))
+}
@@ -0,0 +1,64 @@
+package content
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLoader struct {
+	scheme     string
+	extensions []string
+}
+
+func (l *testLoader) Scheme() string {
+	return l.scheme
+}
+
+func (l *testLoader) Open(context.Context, string) (Source, error) {
+	return nil, nil
+}
+
+type testExtensionLoader struct {
+	testLoader
+}
+
+func (l *testExtensionLoader) Extensions() []string {
+	return l.extensions
+}
+
+func TestRegistry(t *testing.T) {
+	file := &testLoader{scheme: "FILE"}
+	notebook := &testExtensionLoader{testLoader{scheme: "FILE", extensions: []string{".ipynb"}}}
+	url := &testLoader{scheme: "URL"}
+	r := NewRegistry(file, url, notebook)
+
+	assert.Equal(t, []string{"FILE", "URL"}, r.Schemes())
+
+	tests := []struct {
+		prompt   string
+		loader   Loader
+		location string
+	}{
+		{"FILE:main.go", file, "main.go"},
+		{"FILE:nb/Analysis.IPYNB#cells", notebook, "nb/Analysis.IPYNB#cells"},
+		{"URL:https://example.com/a.ipynb crawl", url, "https://example.com/a.ipynb crawl"},
+	}
+	for _, tt := range tests {
+		l, location, ok := r.Find(tt.prompt)
+		assert.True(t, ok, tt.prompt)
+		assert.Same(t, tt.loader, l, tt.prompt)
+		assert.Equal(t, tt.location, location, tt.prompt)
+	}
+
+	for _, p := range []string{"file:main.go", "Note: FILE:main.go", "GIT:diff", "no scheme"} {
+		_, _, ok := r.Find(p)
+		assert.False(t, ok, p)
+	}
+}
+
+func TestContent(t *testing.T) {
+	c := &Content{Parts: []Part{Text("a\n"), &Blob{Data: []byte{1, 2}}, Text("b\n")}}
+	assert.Equal(t, int64(6), c.Size())
+}
@@ -0,0 +1,40 @@
+package loaders
+
+import (
+	"time"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/content/file"
+	"github.com/mchmarny/aictl/pkg/content/git"
+	"github.com/mchmarny/aictl/pkg/content/shell"
+	"github.com/mchmarny/aictl/pkg/content/url"
+)
+
+// Options configures the built-in loaders.
+type Options struct {
+	// MaxSize is the limit of total size of files loaded using single
+	// prompt, Sample and Items limit the included rows of tables and
+	// elements of arrays.
+	MaxSize int64
+	Sample  int
+	Items   int
+
+	// URL configures loading of URL content.
+	URL url.Options
+
+	// CommandTimeout is the time limit of commands, and AllowCommands the
+	// commands which are run without confirmation.
+	CommandTimeout time.Duration
+	AllowCommands  []string
+}
+
+// New returns registry with the built-in loaders: FILE, URL, GIT, and CMD.
+// New loaders are added here, so every chat provider can use them.
+func New(opt Options) *content.Registry {
+	return content.NewRegistry(
+		&file.Loader{MaxSize: opt.MaxSize, Sample: opt.Sample, Items: opt.Items},
+		&url.Loader{Options: opt.URL},
+		&git.Loader{},
+		&shell.Loader{Timeout: opt.CommandTimeout, MaxSize: int(opt.MaxSize), Allow: opt.AllowCommands},
+	)
+}
@@ -0,0 +1,122 @@
+package office
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// documentText returns the paragraphs of Word document. Headings are
+// prefixed with Markdown heading marks, list items with dash, and table
+// rows are printed with cells separated by vertical bars.
+func documentText(r *zip.Reader) (string, error) {
+	rc, err := open(r, "word/document.xml")
+	if err != nil {
+		return "", errors.Wrap(err, "error opening document")
+	}
+	if rc == nil {
+		return "", errors.New("missing word/document.xml")
+	}
+	defer rc.Close()
+
+	var sb strings.Builder
+	var para strings.Builder
+	var prefix string
+	var cells []string
+	depth := 0 // table nesting
+
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "error parsing document")
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				prefix = ""
+			case "pStyle":
+				prefix = headingPrefix(attr(t, "val"))
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "t":
+				var s string
+				if err := d.DecodeElement(&s, &t); err != nil {
+					return "", errors.Wrap(err, "error parsing text")
+				}
+				para.WriteString(s)
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "tbl":
+				depth++
+			case "tr":
+				cells = nil
+			case "tc":
+				cells = append(cells, "")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				txt := strings.TrimSpace(para.String())
+				if depth > 0 && len(cells) > 0 {
+					// paragraphs in table cells are joined into the cell
+					last := len(cells) - 1
+					if cells[last] != "" && txt != "" {
+						cells[last] += " "
+					}
+					cells[last] += txt
+					continue
+				}
+				if txt != "" {
+					sb.WriteString(prefix)
+					sb.WriteString(txt)
+					sb.WriteString("\n")
+				}
+			case "tr":
+				sb.WriteString("| ")
+				sb.WriteString(strings.Join(cells, " | "))
+				sb.WriteString(" |\n")
+			case "tbl":
+				depth--
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// headingPrefix returns Markdown prefix for the paragraph style
+// (e.g. Heading2 or Title).
+func headingPrefix(style string) string {
+	s := strings.ToLower(style)
+	switch {
+	case s == "title":
+		return "# "
+	case strings.HasPrefix(s, "heading"):
+		n, err := strconv.Atoi(strings.TrimSpace(s[len("heading"):]))
+		if err != nil || n < 1 {
+			return ""
+		}
+		if n > 5 {
+			n = 5
+		}
+		return strings.Repeat("#", n+1) + " "
+	case strings.HasPrefix(s, "list"):
+		return "- "
+	}
+	return ""
+}
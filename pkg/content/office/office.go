@@ -0,0 +1,109 @@
+// Package office extracts content from Office Open XML documents: text of
+// Word documents (DOCX) and PowerPoint presentations (PPTX), and sheets of
+// Excel workbooks (XLSX) as tables.
+package office
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DOCX = ".docx"
+	XLSX = ".xlsx"
+	PPTX = ".pptx"
+
+	sheetParam = "sheet="
+)
+
+// IsOffice reports whether the file at path is DOCX, XLSX or PPTX document
+// based on its extension.
+func IsOffice(path string) bool {
+	switch Type(path) {
+	case DOCX, XLSX, PPTX:
+		return true
+	}
+	return false
+}
+
+// Type returns the lower case extension of path without sheet selection.
+func Type(path string) string {
+	p, _ := SplitPath(path)
+	return strings.ToLower(filepath.Ext(p))
+}
+
+// SplitPath splits the optional sheet selection from path
+// (e.g. budget.xlsx#sheet=Q3).
+func SplitPath(path string) (string, string) {
+	i := strings.LastIndex(path, "#")
+	if i < 0 || !strings.HasPrefix(path[i+1:], sheetParam) {
+		return path, ""
+	}
+	return path[:i], path[i+1+len(sheetParam):]
+}
+
+// Text returns the text of DOCX or PPTX document at path.
+func Text(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error opening document: %s", path)
+	}
+	defer r.Close()
+
+	var txt string
+	switch Type(path) {
+	case DOCX:
+		txt, err = documentText(&r.Reader)
+	case PPTX:
+		txt, err = presentationText(&r.Reader)
+	default:
+		return "", errors.Errorf("unsupported document type: %s", path)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading document: %s", path)
+	}
+	return txt, nil
+}
+
+// open returns the named part of the package, or nil when it doesn't exist.
+func open(r *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, f := range r.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, nil
+}
+
+// decode parses the named XML part of the package into v.
+func decode(r *zip.Reader, name string, v interface{}) error {
+	rc, err := open(r, name)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", name)
+	}
+	if rc == nil {
+		return errors.Errorf("missing %s", name)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return errors.Wrapf(err, "error parsing %s", name)
+	}
+	return nil
+}
+
+// attr returns the value of the attribute with local name, ignoring its
+// namespace.
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
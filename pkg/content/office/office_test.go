@@ -0,0 +1,164 @@
+package office
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeZip creates Office package at path with the named parts.
+func writeZip(t *testing.T, path string, parts map[string]string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range parts {
+		pw, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = pw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+}
+
+func TestSplitPath(t *testing.T) {
+	p, sheet := SplitPath("budget.xlsx#sheet=Q3 2023")
+	assert.Equal(t, "budget.xlsx", p)
+	assert.Equal(t, "Q3 2023", sheet)
+	assert.Equal(t, XLSX, Type("Budget.XLSX#sheet=Q3"))
+	assert.True(t, IsOffice("spec.docx"))
+	assert.False(t, IsOffice("spec.doc"))
+}
+
+func TestDocumentText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.docx")
+	writeZip(t, path, map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Product Spec</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Goals</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Ship the </w:t></w:r><w:r><w:t>loader.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>Fast</w:t></w:r></w:p>
+<w:p></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Owner</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Loader</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Ann</w:t></w:r></w:p><w:p><w:r><w:t>Bob</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`,
+	})
+
+	txt, err := Text(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "# Product Spec\n## Goals\nShip the loader.\n- Fast\n| Name | Owner |\n| Loader | Ann Bob |\n", txt)
+
+	_, err = Text(filepath.Join(t.TempDir(), "missing.docx"))
+	assert.Error(t, err)
+}
+
+func TestPresentationText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deck.pptx")
+	slide := func(title, body string) string {
+		return `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+<p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p><a:p><a:r><a:t>` + body + `</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	writeZip(t, path, map[string]string{
+		"ppt/slides/slide10.xml": slide("Summary", "Done"),
+		"ppt/slides/slide2.xml":  slide("Plan", "Q3"),
+		"ppt/slides/slide1.xml":  slide("Intro", "Hello"),
+	})
+
+	txt, err := Text(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "--- slide 1 ---\nIntro\nHello\n--- slide 2 ---\nPlan\nQ3\n--- slide 10 ---\nSummary\nDone\n", txt)
+}
+
+func TestSheets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.xlsx")
+	writeZip(t, path, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Costs" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>item</t></si><si><t>cost</t></si><si><t>day</t></si><si><r><t>Lap</t></r><r><t>top</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="20"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="inlineStr"><is><t>paid</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2"><v>1200.5</v></c><c r="C2" s="1"><v>45292</v></c><c r="D2" t="b"><v>1</v></c></row>
+<row r="4"><c r="A4" t="inlineStr"><is><t>Desk</t></is></c><c r="C4" s="1"><v>45323</v></c><c r="D4" t="b"><v>0</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+	})
+
+	tables, err := Sheets(path, "")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 1)
+
+	tb := tables[0]
+	assert.Equal(t, "budget_Costs", tb.Name)
+	assert.Equal(t, [][]string{
+		{"Laptop", "1200.5", "2024-01-01", "true"},
+		{"", "", "", ""},
+		{"Desk", "", "2024-02-01", "false"},
+	}, tb.Rows)
+	assert.Equal(t, table.Float, tb.Columns[1].Type)
+	assert.Equal(t, table.Date, tb.Columns[2].Type)
+	assert.Equal(t, table.Bool, tb.Columns[3].Type)
+
+	tables, err = Sheets(path, "costs")
+	assert.NoError(t, err)
+	assert.Len(t, tables, 1)
+
+	_, err = Sheets(path, "Missing")
+	assert.ErrorContains(t, err, "available sheets: Costs, Empty")
+}
+
+func TestMalformedSheet(t *testing.T) {
+	sheet := func(rows string) map[string]string {
+		return map[string]string{
+			"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + rows + `</sheetData></worksheet>`,
+		}
+	}
+	path := filepath.Join(t.TempDir(), "data.xlsx")
+
+	// invalid references fall back on the order of cells
+	writeZip(t, path, sheet(`<row r="1"><c r="a1" t="inlineStr"><is><t>name</t></is></c><c r="1" t="inlineStr"><is><t>size</t></is></c></row>
+<row r="2"><c t="inlineStr"><is><t>a</t></is></c><c r="B2"><v>1</v></c></row>`))
+	tables, err := Sheets(path, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "size", tables[0].Columns[1].Name)
+	assert.Equal(t, [][]string{{"a", "1"}}, tables[0].Rows)
+
+	// so do columns beyond the limit
+	writeZip(t, path, sheet(`<row r="1"><c r="ZZZZZZZZZZZZZZZZZZZZ1" t="inlineStr"><is><t>x</t></is></c><c r="XFE1" t="inlineStr"><is><t>y</t></is></c></row>
+<row r="2"><c r="A2"><v>1</v></c><c r="B2"><v>2</v></c></row>`))
+	tables, err = Sheets(path, "")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"1", "2"}}, tables[0].Rows)
+
+	for _, rows := range []string{
+		`<row r="1000000000"><c r="A1000000000"><v>1</v></c></row>`,
+		`<row r="1"><c r="XFD1"><v>1</v></c></row><row r="1048576"><c r="A1048576"><v>1</v></c></row>`,
+	} {
+		writeZip(t, path, sheet(rows))
+		_, err := Sheets(path, "")
+		assert.Error(t, err, rows)
+	}
+}
+
+func TestSerialDate(t *testing.T) {
+	assert.Equal(t, "2024-01-01", serialDate(45292))
+	assert.Equal(t, "2024-01-01 12:00:00", serialDate(45292.5))
+	assert.Equal(t, 27, column("AB3"))
+	assert.Equal(t, -1, column("a1"))
+	assert.Equal(t, -1, column("AAAAAAAA1"))
+}
@@ -0,0 +1,92 @@
+package office
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var slideName = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// presentationText returns the text of each slide preceded by a header
+// with the slide number.
+func presentationText(r *zip.Reader) (string, error) {
+	type slide struct {
+		n int
+		f *zip.File
+	}
+
+	var slides []slide
+	for _, f := range r.File {
+		if m := slideName.FindStringSubmatch(f.Name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			slides = append(slides, slide{n: n, f: f})
+		}
+	}
+	if len(slides) == 0 {
+		return "", errors.New("no slides found")
+	}
+	sort.Slice(slides, func(i, j int) bool { return slides[i].n < slides[j].n })
+
+	var sb strings.Builder
+	for _, s := range slides {
+		txt, err := slideText(s.f)
+		if err != nil {
+			return "", errors.Wrapf(err, "error reading slide %d", s.n)
+		}
+		fmt.Fprintf(&sb, "--- slide %d ---\n%s", s.n, txt)
+	}
+	return sb.String(), nil
+}
+
+// slideText returns the paragraphs of the slide, one per line.
+func slideText(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var sb, para strings.Builder
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+			case "t":
+				var s string
+				if err := d.DecodeElement(&s, &t); err != nil {
+					return "", err
+				}
+				para.WriteString(s)
+			case "br":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			if t.Name.Local == "p" {
+				if txt := strings.TrimSpace(para.String()); txt != "" {
+					sb.WriteString(txt)
+					sb.WriteString("\n")
+				}
+			}
+		}
+	}
+	return sb.String(), nil
+}
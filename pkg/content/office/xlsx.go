@@ -0,0 +1,334 @@
+package office
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/pkg/errors"
+)
+
+// limits of worksheet size, rows and columns are the Excel limits
+const (
+	maxRows  = 1048576
+	maxCols  = 16384
+	maxCells = 10000000
+)
+
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type sharedStrings struct {
+	Items []struct {
+		T    string `xml:"t"`
+		Runs []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type styleSheet struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type worksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string `xml:"r,attr"`
+			S  int    `xml:"s,attr"`
+			T  string `xml:"t,attr"`
+			V  string `xml:"v"`
+			IS struct {
+				T string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// excel serial dates count days from this date
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Sheets returns the sheets of XLSX workbook at path as tables, using the
+// first row of each sheet as header. Empty sheet returns all sheets with
+// data, otherwise only the named one. Cells formatted as dates are
+// converted to ISO dates.
+func Sheets(path, sheet string) ([]*table.Table, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening workbook: %s", path)
+	}
+	defer r.Close()
+
+	tables, err := sheets(&r.Reader, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), sheet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading workbook: %s", path)
+	}
+	return tables, nil
+}
+
+func sheets(r *zip.Reader, name, sheet string) ([]*table.Table, error) {
+	var wb workbook
+	if err := decode(r, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+
+	var rels relationships
+	if err := decode(r, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Items {
+		t := rel.Target
+		if strings.HasPrefix(t, "/") {
+			t = strings.TrimPrefix(t, "/")
+		} else {
+			t = path.Join("xl", t)
+		}
+		targets[rel.ID] = t
+	}
+
+	// shared strings and styles are optional
+	var ss sharedStrings
+	if f, _ := open(r, "xl/sharedStrings.xml"); f != nil {
+		f.Close()
+		if err := decode(r, "xl/sharedStrings.xml", &ss); err != nil {
+			return nil, err
+		}
+	}
+	strs := make([]string, len(ss.Items))
+	for i, si := range ss.Items {
+		s := si.T
+		for _, run := range si.Runs {
+			s += run.T
+		}
+		strs[i] = s
+	}
+
+	var styles styleSheet
+	if f, _ := open(r, "xl/styles.xml"); f != nil {
+		f.Close()
+		if err := decode(r, "xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+	}
+	dates := dateStyles(&styles)
+
+	var tables []*table.Table
+	var names []string
+	for _, s := range wb.Sheets {
+		names = append(names, s.Name)
+		if sheet != "" && !strings.EqualFold(s.Name, sheet) {
+			continue
+		}
+
+		var ws worksheet
+		if err := decode(r, targets[s.ID], &ws); err != nil {
+			return nil, errors.Wrapf(err, "error reading sheet %s", s.Name)
+		}
+
+		rows, err := ws.grid(strs, dates)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading sheet %s", s.Name)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(rows); err != nil {
+			return nil, errors.Wrapf(err, "error converting sheet %s", s.Name)
+		}
+		t, err := table.Read(name+"_"+s.Name, &buf, ',')
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading sheet %s", s.Name)
+		}
+		tables = append(tables, t)
+	}
+
+	if sheet != "" && len(tables) == 0 {
+		return nil, errors.Errorf("sheet %s not found or empty, available sheets: %s", sheet, strings.Join(names, ", "))
+	}
+	if len(tables) == 0 {
+		return nil, errors.New("no data in workbook")
+	}
+	return tables, nil
+}
+
+// grid returns the cell values by row and column. Leading and trailing
+// empty rows are dropped. Cells with invalid reference are placed by their
+// order in the row.
+func (ws *worksheet) grid(strs []string, dates map[int]bool) ([][]string, error) {
+	var rows [][]string
+	width := 0
+	for i, row := range ws.Rows {
+		n := row.R - 1
+		if n < 0 {
+			n = i
+		}
+		if n >= maxRows {
+			return nil, errors.Errorf("invalid row number: %d", row.R)
+		}
+		for len(rows) <= n {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for j, c := range row.Cells {
+			col := j
+			if c.R != "" {
+				if k := column(c.R); k >= 0 {
+					col = k
+				}
+			}
+			if col >= maxCols {
+				return nil, errors.Errorf("too many cells in row %d", n+1)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			v := c.V
+			switch c.T {
+			case "s":
+				if k, err := strconv.Atoi(v); err == nil && k >= 0 && k < len(strs) {
+					v = strs[k]
+				}
+			case "inlineStr":
+				v = c.IS.T
+			case "b":
+				v = strconv.FormatBool(v == "1")
+			case "", "n":
+				if dates[c.S] {
+					if f, err := strconv.ParseFloat(v, 64); err == nil {
+						v = serialDate(f)
+					}
+				}
+			}
+			values[col] = v
+		}
+		if len(values) > width {
+			width = len(values)
+		}
+		rows[n] = values
+	}
+
+	if len(rows)*width > maxCells {
+		return nil, errors.Errorf("sheet too large: %d rows, %d columns", len(rows), width)
+	}
+
+	// pad rows to the same width and drop empty rows at the edges
+	for i := range rows {
+		for len(rows[i]) < width {
+			rows[i] = append(rows[i], "")
+		}
+	}
+	empty := func(r []string) bool {
+		for _, v := range r {
+			if strings.TrimSpace(v) != "" {
+				return false
+			}
+		}
+		return true
+	}
+	for len(rows) > 0 && empty(rows[0]) {
+		rows = rows[1:]
+	}
+	for len(rows) > 0 && empty(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+// column returns zero based column index of cell reference (e.g. AB12),
+// or -1 when the reference isn't valid.
+func column(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+		if n > maxCols {
+			return -1
+		}
+	}
+	return n - 1
+}
+
+// dateStyles returns the indexes of cell styles which format numbers as
+// dates, either using built-in date format or custom one with day or year.
+func dateStyles(s *styleSheet) map[int]bool {
+	custom := map[int]bool{}
+	for _, f := range s.NumFmts {
+		code := strings.ToLower(f.Code)
+		// drop quoted literals and colors (e.g. [Red])
+		for _, q := range []string{`"`, `[`} {
+			for {
+				i := strings.Index(code, q)
+				if i < 0 {
+					break
+				}
+				end := `"`
+				if q == "[" {
+					end = "]"
+				}
+				j := strings.Index(code[i+1:], end)
+				if j < 0 {
+					break
+				}
+				code = code[:i] + code[i+1+j+1:]
+			}
+		}
+		if strings.ContainsAny(code, "dy") {
+			custom[f.ID] = true
+		}
+	}
+
+	dates := map[int]bool{}
+	for i, xf := range s.CellXfs {
+		id := xf.NumFmtID
+		// built-in date formats, 18-21 and 45-47 are times only
+		if (id >= 14 && id <= 17) || id == 22 || custom[id] {
+			dates[i] = true
+		}
+	}
+	return dates
+}
+
+// serialDate converts Excel serial date to ISO date, including time when
+// the value has fractional part.
+func serialDate(f float64) string {
+	days := math.Floor(f)
+	secs := math.Round((f - days) * 86400)
+	t := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second)
+	if secs == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
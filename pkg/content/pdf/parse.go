@@ -0,0 +1,447 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// PDF object types, numbers are int or float64, arrays are []any, and
+// null is nil.
+type (
+	name    string
+	keyword string
+	dict    map[string]any
+
+	ref struct {
+		num int
+		gen int
+	}
+
+	stream struct {
+		dict dict
+		data []byte
+	}
+)
+
+const maxDepth = 32
+
+var objExp = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// document is parsed PDF file. Objects are located by scanning the file
+// rather than reading the cross-reference table, which also works for
+// files with damaged or missing xref.
+type document struct {
+	objects map[int]any
+}
+
+func parse(data []byte) (*document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF document")
+	}
+
+	d := &document{objects: map[int]any{}}
+
+	next := 0
+	for _, m := range objExp.FindAllSubmatchIndex(data, -1) {
+		if m[0] < next {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &lexer{b: data, pos: m[1]}
+		obj := l.object(0)
+		if dt, ok := obj.(dict); ok {
+			if s, ok := l.stream(dt); ok {
+				obj = s
+			}
+		}
+		d.objects[num] = obj
+		next = l.pos
+	}
+
+	// objects compressed in object streams
+	for _, obj := range d.objects {
+		s, ok := obj.(*stream)
+		if !ok || s.dict["Type"] != name("ObjStm") {
+			continue
+		}
+		if err := d.loadObjectStream(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(d.objects) == 0 {
+		return nil, errors.New("no objects found in PDF document")
+	}
+
+	return d, nil
+}
+
+func (d *document) loadObjectStream(s *stream) error {
+	data, err := d.decode(s)
+	if err != nil {
+		return err
+	}
+
+	n, _ := d.resolve(s.dict["N"]).(int)
+	first, ok := d.resolve(s.dict["First"]).(int)
+	if !ok || first < 0 || first > len(data) {
+		return errors.New("invalid object stream")
+	}
+
+	l := &lexer{b: data[:first]}
+	for i := 0; i < n; i++ {
+		num, ok1 := l.object(0).(int)
+		off, ok2 := l.object(0).(int)
+		if !ok1 || !ok2 {
+			break
+		}
+		if off < 0 || first+off > len(data) {
+			return errors.Errorf("invalid offset of object %d in object stream", num)
+		}
+		if _, exists := d.objects[num]; exists {
+			continue
+		}
+		d.objects[num] = (&lexer{b: data, pos: first + off}).object(0)
+	}
+
+	return nil
+}
+
+// resolve follows the indirect references to the referenced object.
+func (d *document) resolve(v any) any {
+	for i := 0; i < maxDepth; i++ {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+		v = d.objects[r.num]
+	}
+	return nil
+}
+
+func (d *document) dict(v any) dict {
+	switch t := d.resolve(v).(type) {
+	case dict:
+		return t
+	case *stream:
+		return t.dict
+	}
+	return nil
+}
+
+func (d *document) array(v any) []any {
+	a, _ := d.resolve(v).([]any)
+	return a
+}
+
+// decode returns the decoded stream data.
+func (d *document) decode(s *stream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case name:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+
+	data := s.data
+	for _, f := range filters {
+		switch d.resolve(f) {
+		case name("FlateDecode"), name("Fl"):
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, errors.Wrap(err, "error decoding stream")
+			}
+			b, err := io.ReadAll(r)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, errors.Wrap(err, "error decoding stream")
+			}
+			data = b
+		case name("ASCIIHexDecode"), name("AHx"):
+			data = decodeHex(data)
+		default:
+			return nil, errors.Errorf("unsupported stream filter: %v", f)
+		}
+	}
+	return data, nil
+}
+
+func decodeHex(b []byte) []byte {
+	var clean []byte
+	for _, c := range b {
+		if c == '>' {
+			break
+		}
+		if isHex(c) {
+			clean = append(clean, c)
+		}
+	}
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+	out := make([]byte, len(clean)/2)
+	_, _ = hex.Decode(out, clean)
+	return out
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// lexer reads PDF objects from the underlying bytes.
+type lexer struct {
+	b   []byte
+	pos int
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isDelim(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func (l *lexer) eof() bool {
+	return l.pos < 0 || l.pos >= len(l.b)
+}
+
+func (l *lexer) skipSpace() {
+	for !l.eof() {
+		c := l.b[l.pos]
+		switch {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for !l.eof() && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) regular() string {
+	if l.eof() {
+		return ""
+	}
+	start := l.pos
+	for !l.eof() && !isSpace(l.b[l.pos]) && !isDelim(l.b[l.pos]) {
+		l.pos++
+	}
+	return string(l.b[start:l.pos])
+}
+
+// object reads the next object, operators in content streams are returned
+// as keyword. At the end of input nil is returned.
+func (l *lexer) object(depth int) any {
+	l.skipSpace()
+	if l.eof() || depth > maxDepth {
+		return nil
+	}
+
+	c := l.b[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return name(decodeName(l.regular()))
+	case c == '(':
+		return l.literal()
+	case c == '<' && l.pos+1 < len(l.b) && l.b[l.pos+1] == '<':
+		l.pos += 2
+		dt := dict{}
+		for {
+			l.skipSpace()
+			if l.eof() {
+				return dt
+			}
+			if l.b[l.pos] == '>' {
+				l.pos = min(l.pos+2, len(l.b))
+				return dt
+			}
+			k, ok := l.object(depth + 1).(name)
+			if !ok {
+				continue
+			}
+			dt[string(k)] = l.object(depth + 1)
+		}
+	case c == '<':
+		l.pos++
+		start := l.pos
+		for !l.eof() && l.b[l.pos] != '>' {
+			l.pos++
+		}
+		s := decodeHex(l.b[start:l.pos])
+		if !l.eof() {
+			l.pos++
+		}
+		return string(s)
+	case c == '[':
+		l.pos++
+		arr := []any{}
+		for {
+			l.skipSpace()
+			if l.eof() {
+				return arr
+			}
+			if l.b[l.pos] == ']' {
+				l.pos++
+				return arr
+			}
+			arr = append(arr, l.object(depth+1))
+		}
+	case c == ')' || c == '>' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return keyword(c)
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number()
+	}
+
+	switch k := l.regular(); k {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	default:
+		return keyword(k)
+	}
+}
+
+// number reads a number or an indirect reference (e.g. 12 0 R).
+func (l *lexer) number() any {
+	tok := l.regular()
+	i, err := strconv.Atoi(tok)
+	if err != nil {
+		f, _ := strconv.ParseFloat(tok, 64)
+		return f
+	}
+
+	save := l.pos
+	l.skipSpace()
+	gen, err := strconv.Atoi(l.regular())
+	if err == nil {
+		l.skipSpace()
+		if !l.eof() && l.b[l.pos] == 'R' && (l.pos+1 == len(l.b) || isSpace(l.b[l.pos+1]) || isDelim(l.b[l.pos+1])) {
+			l.pos++
+			return ref{num: i, gen: gen}
+		}
+	}
+	l.pos = save
+	return i
+}
+
+func (l *lexer) literal() string {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for !l.eof() {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(out)
+			}
+		case '\\':
+			if l.eof() {
+				break
+			}
+			e := l.b[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if !l.eof() && l.b[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && !l.eof() && l.b[l.pos] >= '0' && l.b[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.b[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// stream reads the stream data following the stream dictionary.
+func (l *lexer) stream(dt dict) (*stream, bool) {
+	l.skipSpace()
+	if l.eof() || !bytes.HasPrefix(l.b[l.pos:], []byte("stream")) {
+		return nil, false
+	}
+	l.pos += len("stream")
+	if l.pos < len(l.b) && l.b[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.b) && l.b[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	// use length when it's direct and points at the end of stream
+	if n, ok := dt["Length"].(int); ok && n >= 0 && start+n <= len(l.b) {
+		rest := bytes.TrimLeft(l.b[start+n:], " \t\r\n")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = start + n
+			return &stream{dict: dt, data: l.b[start : start+n]}, true
+		}
+	}
+
+	end := bytes.Index(l.b[start:], []byte("endstream"))
+	if end < 0 {
+		end = len(l.b) - start
+	}
+	data := bytes.TrimRight(l.b[start:start+end], "\r\n")
+	l.pos = start + end
+	return &stream{dict: dt, data: data}, true
+}
+
+func decodeName(s string) string {
+	if !bytes.ContainsRune([]byte(s), '#') {
+		return s
+	}
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			b, _ := hex.DecodeString(s[i+1 : i+3])
+			out = append(out, b...)
+			i += 2
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,143 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Extension is the file extension of PDF documents.
+	Extension = ".pdf"
+
+	pagesParam = "pages="
+)
+
+// IsPDF reports whether the file at path is a PDF document based on its
+// extension.
+func IsPDF(path string) bool {
+	p, _ := SplitPath(path)
+	return strings.EqualFold(filepath.Ext(p), Extension)
+}
+
+// SplitPath splits the optional page selection from path
+// (e.g. report.pdf#pages=3-7).
+func SplitPath(path string) (string, string) {
+	i := strings.LastIndex(path, "#")
+	if i < 0 || !strings.HasPrefix(path[i+1:], pagesParam) {
+		return path, ""
+	}
+	return path[:i], path[i+1+len(pagesParam):]
+}
+
+// GetContent returns the description followed by the text of PDF document
+// at path, which can include page selection (e.g. report.pdf#pages=3-7).
+func GetContent(desc, path string) (string, error) {
+	p, pages := SplitPath(path)
+	txt, err := Text(p, pages)
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+	content.WriteString(desc)
+	content.WriteString("\n")
+	content.WriteString(txt)
+
+	return content.String(), nil
+}
+
+// Text returns the text of the selected pages of PDF document at path,
+// each page is preceded by a header with its number. Empty pages selects
+// the entire document.
+func Text(path, pages string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading file: %s", path)
+	}
+
+	txt, err := ReadText(b, pages)
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading PDF: %s", path)
+	}
+	return txt, nil
+}
+
+// ReadText returns the text of the selected pages of PDF document data
+// (see Text).
+func ReadText(b []byte, pages string) (string, error) {
+	doc, err := parse(b)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing PDF")
+	}
+
+	list := doc.pages()
+	if len(list) == 0 {
+		return "", errors.New("no pages found in PDF")
+	}
+
+	selected, err := ParsePages(pages, len(list))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, n := range selected {
+		fmt.Fprintf(&sb, "--- page %d ---\n", n)
+		if txt := doc.text(list[n-1]); txt != "" {
+			sb.WriteString(txt)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// ParsePages returns page numbers selected by spec (e.g. "1,3-5,8-")
+// in document with total number of pages. Empty spec selects all pages.
+func ParsePages(spec string, total int) ([]int, error) {
+	var pages []int
+	if strings.TrimSpace(spec) == "" {
+		for i := 1; i <= total; i++ {
+			pages = append(pages, i)
+		}
+		return pages, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+
+		start, err := strconv.Atoi(from)
+		if err != nil || start < 1 {
+			return nil, errors.Errorf("invalid page range: %s", part)
+		}
+
+		end := start
+		if isRange {
+			end = total
+			if to != "" {
+				if end, err = strconv.Atoi(to); err != nil || end < start {
+					return nil, errors.Errorf("invalid page range: %s", part)
+				}
+			}
+		}
+
+		if start > total {
+			return nil, errors.Errorf("page %d out of range, document has %d pages", start, total)
+		}
+		if end > total {
+			end = total
+		}
+
+		for i := start; i <= end; i++ {
+			pages = append(pages, i)
+		}
+	}
+
+	return pages, nil
+}
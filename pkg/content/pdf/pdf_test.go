@@ -0,0 +1,138 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCMap = `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0001> <00C9> endbfchar
+1 beginbfrange <0002> <0004> <0061> endbfrange
+endcmap`
+
+// writePDF creates PDF document with a page for each of the contents.
+func writePDF(t *testing.T, contents ...string) string {
+	t.Helper()
+
+	var objs []string
+	add := func(s string) int {
+		objs = append(objs, s)
+		return len(objs)
+	}
+	flate := func(s string) string {
+		var b bytes.Buffer
+		w := zlib.NewWriter(&b)
+		_, _ = w.Write([]byte(s))
+		_ = w.Close()
+		return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", b.Len(), b.String())
+	}
+
+	catalog := add("")
+	pages := add("")
+	cmap := add(flate(testCMap))
+	font1 := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	font2 := add(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /X /ToUnicode %d 0 R >>", cmap))
+
+	var kids string
+	for _, c := range contents {
+		content := add(flate(c))
+		p := add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents %d 0 R >>", pages, content))
+		kids += fmt.Sprintf("%d 0 R ", p)
+	}
+	objs[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages)
+	objs[pages-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		kids, len(contents), font1, font2)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	for i, o := range objs {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Root %d 0 R >>\n%%%%EOF\n", catalog)
+
+	p := filepath.Join(t.TempDir(), "test.pdf")
+	assert.NoError(t, os.WriteFile(p, b.Bytes(), 0o600))
+	return p
+}
+
+func TestText(t *testing.T) {
+	p := writePDF(t,
+		"BT /F1 12 Tf 72 712 Td (Hello \\(PDF\\)) Tj 0 -14 Td [(Second) -300 (line)] TJ ET",
+		"BT /F2 12 Tf 72 712 Td <0001000200030004> Tj ET",
+		"BT /F1 12 Tf 72 712 Td (Third page) Tj ET",
+	)
+
+	t.Run("All pages", func(t *testing.T) {
+		txt, err := Text(p, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "--- page 1 ---\nHello (PDF)\nSecond line\n--- page 2 ---\nÉabc\n--- page 3 ---\nThird page\n", txt)
+	})
+
+	t.Run("Page range", func(t *testing.T) {
+		content, err := GetContent("desc", p+"#pages=2-")
+		assert.NoError(t, err)
+		assert.Equal(t, "desc\n--- page 2 ---\nÉabc\n--- page 3 ---\nThird page\n", content)
+	})
+
+	t.Run("Invalid range", func(t *testing.T) {
+		_, err := GetContent("desc", p+"#pages=4")
+		assert.Error(t, err)
+	})
+
+	t.Run("Not PDF", func(t *testing.T) {
+		_, err := Text("../../../content/annual-us-gdp.csv", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestParsePages(t *testing.T) {
+	pages, err := ParsePages("1,3-4,9-", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3, 4, 9, 10}, pages)
+
+	for _, spec := range []string{"0", "a", "3-2", "11"} {
+		_, err := ParsePages(spec, 10)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	p, pages := SplitPath("a/report.pdf#pages=3-7")
+	assert.Equal(t, "a/report.pdf", p)
+	assert.Equal(t, "3-7", pages)
+	assert.True(t, IsPDF("a/REPORT.PDF#pages=1"))
+	assert.False(t, IsPDF("a/notes#1.txt"))
+}
+
+func TestMalformed(t *testing.T) {
+	objStm := func(dict, data string) []byte {
+		return []byte(fmt.Sprintf("%%PDF-1.5\n1 0 obj\n<< /Type /ObjStm %s /Length %d >>\nstream\n%s\nendstream\nendobj\n", dict, len(data), data))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Negative first", objStm("/N 1 /First -5", "2 0 << /Type /Page >>")},
+		{"Negative offset", objStm("/N 1 /First 6", "2 -9  << /Type /Page >>")},
+		{"Offset out of range", objStm("/N 1 /First 6", "2 900 << /Type /Page >>")},
+		{"First out of range", objStm("/N 1 /First 900", "2 0 << /Type /Page >>")},
+		{"Truncated dictionary", []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages <")},
+		{"Truncated stream", []byte("%PDF-1.4\n1 0 obj\n<< /Length 10 >>\nstream")},
+		{"Truncated hex string", []byte("%PDF-1.4\n1 0 obj\n<< /Title <4142")},
+	}
+	for _, tt := range tests {
+		assert.NotPanics(t, func() {
+			_, err := ReadText(tt.data, "")
+			assert.Error(t, err, tt.name)
+		}, tt.name)
+	}
+}
@@ -0,0 +1,399 @@
+package pdf
+
+import (
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// page is a leaf of the page tree with its inherited resources.
+type page struct {
+	dict      dict
+	resources dict
+}
+
+// pages returns the document pages in order.
+func (d *document) pages() []*page {
+	var root dict
+	for _, obj := range d.objects {
+		if dt := d.dict(obj); dt != nil && dt["Type"] == name("Catalog") {
+			if p := d.dict(dt["Pages"]); p != nil {
+				root = p
+				break
+			}
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	var list []*page
+	var walk func(node dict, res dict, depth int)
+	walk = func(node dict, res dict, depth int) {
+		if depth > maxDepth {
+			return
+		}
+		if r := d.dict(node["Resources"]); r != nil {
+			res = r
+		}
+		if node["Type"] == name("Page") {
+			list = append(list, &page{dict: node, resources: res})
+			return
+		}
+		for _, kid := range d.array(node["Kids"]) {
+			if k := d.dict(kid); k != nil {
+				walk(k, res, depth+1)
+			}
+		}
+	}
+	walk(root, nil, 0)
+
+	return list
+}
+
+// text returns the text of the page.
+func (d *document) text(p *page) string {
+	var content []byte
+	for _, c := range d.contents(p.dict["Contents"]) {
+		content = append(content, c...)
+		content = append(content, '\n')
+	}
+
+	e := &extractor{doc: d}
+	e.run(content, p.resources, 0)
+	return e.String()
+}
+
+func (d *document) contents(v any) [][]byte {
+	var list [][]byte
+	switch t := d.resolve(v).(type) {
+	case *stream:
+		if b, err := d.decode(t); err == nil {
+			list = append(list, b)
+		}
+	case []any:
+		for _, item := range t {
+			list = append(list, d.contents(item)...)
+		}
+	}
+	return list
+}
+
+// extractor interprets the text operators of page content stream.
+type extractor struct {
+	doc  *document
+	sb   strings.Builder
+	font *font
+	y    float64
+}
+
+func (e *extractor) String() string {
+	lines := strings.Split(e.sb.String(), "\n")
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (e *extractor) newline() {
+	e.sb.WriteString("\n")
+}
+
+func (e *extractor) space() {
+	e.sb.WriteString(" ")
+}
+
+func (e *extractor) show(s string) {
+	if e.font != nil {
+		e.sb.WriteString(e.font.decode(s))
+		return
+	}
+	e.sb.WriteString(latin1(s))
+}
+
+func (e *extractor) run(content []byte, res dict, depth int) {
+	if depth > maxDepth {
+		return
+	}
+
+	fonts := e.doc.dict(res["Font"])
+	xobjects := e.doc.dict(res["XObject"])
+
+	l := &lexer{b: content}
+	var args []any
+	for {
+		l.skipSpace()
+		if l.eof() {
+			return
+		}
+		obj := l.object(0)
+		op, ok := obj.(keyword)
+		if !ok {
+			args = append(args, obj)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			e.font = nil
+		case "ET":
+			e.space()
+		case "Tf":
+			if len(args) >= 2 {
+				if n, ok := args[len(args)-2].(name); ok {
+					e.font = e.doc.font(fonts[string(n)])
+				}
+			}
+		case "Td", "TD":
+			if len(args) >= 2 && num(args[len(args)-1]) != 0 {
+				e.newline()
+			} else {
+				e.space()
+			}
+		case "Tm":
+			if len(args) >= 6 {
+				y := num(args[5])
+				if math.Abs(y-e.y) > 0.1 {
+					e.newline()
+				} else {
+					e.space()
+				}
+				e.y = y
+			}
+		case "T*":
+			e.newline()
+		case "Tj":
+			if len(args) > 0 {
+				if s, ok := args[len(args)-1].(string); ok {
+					e.show(s)
+				}
+			}
+		case "'", "\"":
+			e.newline()
+			if len(args) > 0 {
+				if s, ok := args[len(args)-1].(string); ok {
+					e.show(s)
+				}
+			}
+		case "TJ":
+			if len(args) > 0 {
+				arr, _ := args[len(args)-1].([]any)
+				for _, item := range arr {
+					switch v := item.(type) {
+					case string:
+						e.show(v)
+					case int, float64:
+						// large negative adjustment is a word gap
+						if num(v) < -200 {
+							e.space()
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(args) > 0 {
+				if n, ok := args[len(args)-1].(name); ok {
+					e.form(xobjects[string(n)], res, depth)
+				}
+			}
+		case "BI":
+			// skip inline image data
+			if l.eof() {
+				break
+			}
+			if i := strings.Index(string(content[l.pos:]), "EI"); i >= 0 {
+				l.pos += i + 2
+			}
+		}
+		args = args[:0]
+	}
+}
+
+// form extracts text from form XObject.
+func (e *extractor) form(v any, res dict, depth int) {
+	s, ok := e.doc.resolve(v).(*stream)
+	if !ok || s.dict["Subtype"] != name("Form") {
+		return
+	}
+	b, err := e.doc.decode(s)
+	if err != nil {
+		return
+	}
+	if r := e.doc.dict(s.dict["Resources"]); r != nil {
+		res = r
+	}
+	font := e.font
+	e.run(b, res, depth+1)
+	e.font = font
+}
+
+func num(v any) float64 {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case float64:
+		return t
+	}
+	return 0
+}
+
+func latin1(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 32 && c != '\t' {
+			continue
+		}
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
+
+// font maps character codes to text using the font ToUnicode CMap.
+type font struct {
+	codeLen int
+	cmap    map[uint32]string
+}
+
+func (d *document) font(v any) *font {
+	fd := d.dict(v)
+	if fd == nil {
+		return nil
+	}
+
+	f := &font{codeLen: 1}
+	if fd["Subtype"] == name("Type0") {
+		f.codeLen = 2
+	}
+
+	if s, ok := d.resolve(fd["ToUnicode"]).(*stream); ok {
+		if b, err := d.decode(s); err == nil {
+			f.parseCMap(b)
+		}
+	}
+
+	if f.cmap == nil && f.codeLen == 1 {
+		return nil
+	}
+	return f
+}
+
+func (f *font) decode(s string) string {
+	var sb strings.Builder
+	for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+		var code uint32
+		for j := 0; j < f.codeLen; j++ {
+			code = code<<8 | uint32(s[i+j])
+		}
+		if t, ok := f.cmap[code]; ok {
+			sb.WriteString(t)
+		} else if f.codeLen == 1 {
+			sb.WriteString(latin1(s[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+// parseCMap reads the codespace, bfchar and bfrange sections of CMap.
+func (f *font) parseCMap(b []byte) {
+	f.cmap = map[uint32]string{}
+	l := &lexer{b: b}
+	var args []any
+	section := ""
+	for {
+		l.skipSpace()
+		if l.eof() {
+			return
+		}
+		obj := l.object(0)
+		op, ok := obj.(keyword)
+		if !ok {
+			args = append(args, obj)
+			switch section {
+			case "bfchar":
+				if len(args) == 2 {
+					src, _ := args[0].(string)
+					dst, _ := args[1].(string)
+					f.cmap[code(src)] = utf16be(dst)
+					args = args[:0]
+				}
+			case "bfrange":
+				if len(args) == 3 {
+					f.addRange(args)
+					args = args[:0]
+				}
+			}
+			continue
+		}
+
+		switch op {
+		case "begincodespacerange":
+			section = "codespace"
+		case "endcodespacerange":
+			if len(args) > 0 {
+				if s, ok := args[0].(string); ok && len(s) > 0 {
+					f.codeLen = len(s)
+				}
+			}
+			section = ""
+		case "beginbfchar":
+			section = "bfchar"
+		case "beginbfrange":
+			section = "bfrange"
+		case "endbfchar", "endbfrange":
+			section = ""
+		}
+		args = args[:0]
+	}
+}
+
+func (f *font) addRange(args []any) {
+	lo, _ := args[0].(string)
+	hi, _ := args[1].(string)
+	start, end := code(lo), code(hi)
+	if end < start || end-start > 0xFFFF {
+		return
+	}
+
+	switch dst := args[2].(type) {
+	case string:
+		units := toUnits(dst)
+		if len(units) == 0 {
+			return
+		}
+		for c := start; c <= end; c++ {
+			u := append([]uint16{}, units...)
+			u[len(u)-1] += uint16(c - start)
+			f.cmap[c] = string(utf16.Decode(u))
+		}
+	case []any:
+		for i, item := range dst {
+			if s, ok := item.(string); ok && start+uint32(i) <= end {
+				f.cmap[start+uint32(i)] = utf16be(s)
+			}
+		}
+	}
+}
+
+func code(s string) uint32 {
+	var c uint32
+	for i := 0; i < len(s); i++ {
+		c = c<<8 | uint32(s[i])
+	}
+	return c
+}
+
+func toUnits(s string) []uint16 {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return units
+}
+
+func utf16be(s string) string {
+	return string(utf16.Decode(toUnits(s)))
+}
@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/pkg/errors"
+)
+
+// Scheme is the prompt prefix of commands (e.g. CMD:go test ./...).
+const Scheme = "CMD"
+
+// Loader runs command and loads its output (see Run). Commands have to
+// be confirmed before they are run, unless they are in Allow (see
+// Allowed).
+type Loader struct {
+	Dir     string
+	Timeout time.Duration
+	MaxSize int
+	Allow   []string
+}
+
+func (l *Loader) Scheme() string {
+	return Scheme
+}
+
+func (l *Loader) Open(_ context.Context, location string) (content.Source, error) {
+	command := strings.TrimSpace(location)
+	if command == "" {
+		return nil, errors.New("command not set")
+	}
+	return &source{loader: l, command: command}, nil
+}
+
+type source struct {
+	loader  *Loader
+	command string
+}
+
+func (s *source) Name() string {
+	return Scheme + ":" + s.command
+}
+
+func (s *source) Size() int64 {
+	return -1
+}
+
+func (s *source) Info() []string {
+	return []string{"$ " + s.command}
+}
+
+// Confirm asks to confirm running the command which isn't allowed.
+func (s *source) Confirm() string {
+	if Allowed(s.command, s.loader.Allow) {
+		return ""
+	}
+	return "Run the command?"
+}
+
+func (s *source) Load(ctx context.Context, desc string) (*content.Content, error) {
+	r, err := Run(ctx, s.command, s.loader.Dir, s.loader.Timeout, s.loader.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	status := fmt.Sprintf("Command exited with code %d.", r.ExitCode)
+	if r.TimedOut {
+		status = fmt.Sprintf("Command timed out after %s.", r.Duration.Round(time.Second))
+	}
+	return &content.Content{
+		Parts: []content.Part{content.Text(desc + "\n" + r.String())},
+		Info:  []string{status},
+	}, nil
+}
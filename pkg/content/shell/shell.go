@@ -0,0 +1,149 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TimeoutDefault is the default time limit of running command.
+	TimeoutDefault = 30 * time.Second
+	// MaxSizeDefault is the default limit of captured output of each
+	// stream, only the end of longer output is kept.
+	MaxSizeDefault = 100 * 1024
+
+	// operators which chain or substitute commands, so allowed prefix
+	// doesn't say what is run
+	operators = ";&|<>`$(){}\n"
+)
+
+// Result is the output of command.
+type Result struct {
+	Command  string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+	Duration time.Duration
+}
+
+// Run runs the command using the system shell in dir (current directory
+// when empty), and returns its output and exit code. Non-zero exit code
+// isn't an error. Commands running longer than timeout are killed, the
+// output captured until then is returned.
+func Run(ctx context.Context, command, dir string, timeout time.Duration, maxSize int) (*Result, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, errors.New("command not set")
+	}
+	if timeout <= 0 {
+		timeout = TimeoutDefault
+	}
+	if maxSize <= 0 {
+		maxSize = MaxSizeDefault
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	name, args := "sh", []string{"-c", command}
+	if runtime.GOOS == "windows" {
+		name, args = "cmd", []string{"/C", command}
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	// don't wait for output of background processes started by command
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	r := &Result{
+		Command:  command,
+		Stdout:   tail(stdout.String(), maxSize),
+		Stderr:   tail(stderr.String(), maxSize),
+		Duration: time.Since(start),
+		TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case r.TimedOut:
+		r.ExitCode = -1
+	case errors.As(err, &exitErr):
+		r.ExitCode = exitErr.ExitCode()
+	default:
+		return nil, errors.Wrapf(err, "error running command: %s", command)
+	}
+	return r, nil
+}
+
+// String returns the command and its labeled output.
+func (r *Result) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "$ %s\n", r.Command)
+	if r.TimedOut {
+		fmt.Fprintf(&sb, "Timed out after %s\n", r.Duration.Round(time.Second))
+	} else {
+		fmt.Fprintf(&sb, "Exit code: %d\n", r.ExitCode)
+	}
+	for _, s := range []struct{ name, out string }{{"stdout", r.Stdout}, {"stderr", r.Stderr}} {
+		if s.out == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "--- %s ---\n%s", s.name, s.out)
+		if !strings.HasSuffix(s.out, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// Allowed returns true when the command starts with one of the allowed
+// commands (e.g. "kubectl get" allows "kubectl get pods"), and doesn't
+// include shell operators which would run other commands.
+func Allowed(command string, allow []string) bool {
+	if strings.ContainsAny(command, operators) {
+		return false
+	}
+	f := strings.Fields(command)
+	for _, a := range allow {
+		af := strings.Fields(a)
+		if len(af) == 0 || len(af) > len(f) {
+			continue
+		}
+		match := true
+		for i := range af {
+			if af[i] != f[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// tail returns the end of s up to n bytes.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := len(s) - n
+	if i := strings.IndexByte(s[cut:], '\n'); i >= 0 {
+		cut += i + 1
+	}
+	return fmt.Sprintf("... (%d bytes omitted)\n%s", cut, s[cut:])
+}
@@ -0,0 +1,91 @@
+package shell
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+
+	t.Run("Output", func(t *testing.T) {
+		r, err := Run(ctx, "echo out; echo err >&2; exit 3", "", 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r.ExitCode)
+		assert.Equal(t, "$ echo out; echo err >&2; exit 3\nExit code: 3\n--- stdout ---\nout\n--- stderr ---\nerr\n", r.String())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		r, err := Run(ctx, "echo started; sleep 5", "", 100*time.Millisecond, 0)
+		assert.NoError(t, err)
+		assert.True(t, r.TimedOut)
+		assert.Equal(t, "started\n", r.Stdout)
+		assert.Contains(t, r.String(), "Timed out after")
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		r, err := Run(ctx, "seq 1 1000", "", 0, 20)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(r.Stdout, "... ("))
+		assert.True(t, strings.HasSuffix(r.Stdout, "\n999\n1000\n"))
+	})
+
+	_, err := Run(ctx, " ", "", 0, 0)
+	assert.Error(t, err)
+}
+
+func TestAllowed(t *testing.T) {
+	allow := []string{"kubectl get", "go test", " "}
+	tests := map[string]bool{
+		"kubectl get pods -A":         true,
+		"go test ./...":               true,
+		"go  test":                    true,
+		"kubectl delete pod x":        false,
+		"kubectl":                     false,
+		"go testing":                  false,
+		"go test ./...; rm -rf /tmp/": false,
+		"kubectl get pods | sh":       false,
+		"kubectl get $(cat x)":        false,
+		"go test > out.txt":           false,
+	}
+	for cmd, want := range tests {
+		assert.Equal(t, want, Allowed(cmd, allow), cmd)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+	l := &Loader{Allow: []string{"echo"}}
+
+	_, err := l.Open(ctx, " ")
+	assert.Error(t, err)
+
+	src, err := l.Open(ctx, " echo hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "CMD:echo hello", src.Name())
+	assert.Equal(t, []string{"$ echo hello"}, src.Info())
+	assert.Empty(t, src.(*source).Confirm())
+
+	c, err := src.Load(ctx, "desc")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Command exited with code 0."}, c.Info)
+	txt, err := content.JoinText(c.Parts)
+	assert.NoError(t, err)
+	assert.Equal(t, "desc\n$ echo hello\nExit code: 0\n--- stdout ---\nhello\n", txt)
+
+	src, err = l.Open(ctx, "echo a; echo b")
+	assert.NoError(t, err)
+	assert.Equal(t, "Run the command?", src.(*source).Confirm())
+}
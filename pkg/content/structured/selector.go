@@ -0,0 +1,252 @@
+package structured
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// step is single selector step: object key, array index, slice, or
+// iteration over all elements.
+type step struct {
+	key     string
+	index   *int
+	from    *int
+	to      *int
+	iterate bool
+	slice   bool
+}
+
+// Select returns the value of doc selected by jq-like selector:
+// .key or ."quoted key" selects object key, [N] array element (negative
+// counts from the end), [N:M] array slice, and [] iterates over array
+// elements or object values. When the selector iterates, the selected
+// values are returned as array.
+func Select(doc any, selector string) (any, error) {
+	steps, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []any{doc}
+	multi := false
+	for _, s := range steps {
+		var next []any
+		for _, v := range values {
+			out, err := s.apply(v, multi)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		values = next
+		multi = multi || s.iterate || s.slice
+	}
+
+	if multi {
+		if values == nil {
+			values = []any{}
+		}
+		return values, nil
+	}
+	return values[0], nil
+}
+
+func (s *step) apply(v any, lenient bool) ([]any, error) {
+	switch {
+	case s.iterate:
+		switch v := v.(type) {
+		case []any:
+			return v, nil
+		case map[string]any:
+			list := make([]any, 0, len(v))
+			for _, k := range sortedKeys(v) {
+				list = append(list, v[k])
+			}
+			return list, nil
+		}
+		if lenient {
+			return nil, nil
+		}
+		return nil, errors.Errorf("cannot iterate over %s", kind(v))
+	case s.index != nil || s.slice:
+		list, ok := v.([]any)
+		if !ok {
+			if lenient {
+				return nil, nil
+			}
+			return nil, errors.Errorf("cannot index %s", kind(v))
+		}
+		if s.slice {
+			from, to := bound(s.from, 0, len(list)), bound(s.to, len(list), len(list))
+			if from >= to {
+				return nil, nil
+			}
+			return list[from:to], nil
+		}
+		i := *s.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			if lenient {
+				return nil, nil
+			}
+			return nil, errors.Errorf("index %d out of range (%d elements)", *s.index, len(list))
+		}
+		return []any{list[i]}, nil
+	default:
+		m, ok := v.(map[string]any)
+		if !ok {
+			if lenient {
+				return nil, nil
+			}
+			return nil, errors.Errorf("cannot select key %q in %s", s.key, kind(v))
+		}
+		x, ok := m[s.key]
+		if !ok {
+			if lenient {
+				return nil, nil
+			}
+			return nil, errors.Errorf("key %q not found", s.key)
+		}
+		return []any{x}, nil
+	}
+}
+
+func bound(p *int, def, n int) int {
+	if p == nil {
+		return def
+	}
+	i := *p
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+func parseSelector(sel string) ([]*step, error) {
+	var steps []*step
+	s := strings.TrimSpace(sel)
+	if s == "" || s == "." {
+		return nil, nil
+	}
+
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".\""), strings.HasPrefix(s, "[\""):
+			bracket := s[0] == '['
+			end := strings.Index(s[2:], "\"")
+			if end < 0 {
+				return nil, errors.Errorf("unterminated quote in selector: %s", sel)
+			}
+			steps = append(steps, &step{key: s[2 : 2+end]})
+			s = s[2+end+1:]
+			if bracket {
+				if !strings.HasPrefix(s, "]") {
+					return nil, errors.Errorf("expected ] in selector: %s", sel)
+				}
+				s = s[1:]
+			}
+		case strings.HasPrefix(s, "["):
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, errors.Errorf("expected ] in selector: %s", sel)
+			}
+			st, err := parseIndex(strings.TrimSpace(s[1:end]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid selector: %s", sel)
+			}
+			steps = append(steps, st)
+			s = s[end+1:]
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				// e.g. .[] or .[0]
+				if strings.HasPrefix(s, "[") {
+					continue
+				}
+				return nil, errors.Errorf("empty key in selector: %s", sel)
+			}
+			steps = append(steps, &step{key: s[:end]})
+			s = s[end:]
+		default:
+			return nil, errors.Errorf("invalid selector: %s", sel)
+		}
+	}
+
+	return steps, nil
+}
+
+func parseIndex(s string) (*step, error) {
+	if s == "" {
+		return &step{iterate: true}, nil
+	}
+
+	if i := strings.Index(s, ":"); i >= 0 {
+		st := &step{slice: true}
+		var err error
+		if st.from, err = optInt(s[:i]); err != nil {
+			return nil, err
+		}
+		if st.to, err = optInt(s[i+1:]); err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, errors.Errorf("invalid index: %s", s)
+	}
+	return &step{index: &n}, nil
+}
+
+func optInt(s string) (*int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, errors.Errorf("invalid index: %s", s)
+	}
+	return &n, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func kind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return "number"
+}
@@ -0,0 +1,184 @@
+// Package structured loads JSON and YAML documents, optionally selecting
+// subtree by path (e.g. state.json#.items[].metadata). Large arrays are
+// summarized and the output is minified JSON to save tokens.
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ItemsDefault is the default number of elements included from arrays.
+const ItemsDefault = 10
+
+// IsStructured reports whether the file at path is JSON or YAML document
+// based on its extension.
+func IsStructured(path string) bool {
+	p, _ := SplitPath(path)
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// SplitPath splits the optional selector from path
+// (e.g. state.json#.items[].metadata).
+func SplitPath(path string) (string, string) {
+	i := strings.LastIndex(path, "#")
+	if i < 0 || !strings.HasPrefix(path[i+1:], ".") {
+		return path, ""
+	}
+	return path[:i], path[i+1:]
+}
+
+// Load reads the document at path and returns the value selected by
+// selector as minified JSON. Arrays with more than items elements are
+// cut to the first items elements followed by the number of the omitted
+// ones. Empty selector returns the entire document.
+func Load(path, selector string, items int) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error reading file: %s", path)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	txt, err := Read(b, ext == ".yaml" || ext == ".yml", selector, items)
+	if err != nil {
+		return "", errors.Wrapf(err, "error loading file: %s", path)
+	}
+	return txt, nil
+}
+
+// Read parses JSON (or YAML) document data and returns the selected value
+// as minified JSON (see Load).
+func Read(b []byte, isYAML bool, selector string, items int) (string, error) {
+	var doc any
+	var err error
+	if isYAML {
+		doc, err = parseYAML(b)
+	} else {
+		doc, err = parseJSON(b)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing document")
+	}
+
+	v, err := Select(doc, selector)
+	if err != nil {
+		return "", errors.Wrapf(err, "error selecting %s", selector)
+	}
+
+	return Minify(Summarize(v, items))
+}
+
+func parseJSON(b []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	// the document has to be single value
+	if _, err := d.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// parseYAML parses the YAML stream, which can hold multiple documents
+// returned as array.
+func parseYAML(b []byte) (any, error) {
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	var docs []any
+	for {
+		var v any
+		err := d.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, normalize(v))
+	}
+
+	switch len(docs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// normalize converts YAML maps with non-string keys so they can be
+// encoded as JSON.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			v[k] = normalize(x)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, x := range v {
+			m[fmt.Sprint(k)] = normalize(x)
+		}
+		return m
+	case []any:
+		for i, x := range v {
+			v[i] = normalize(x)
+		}
+		return v
+	}
+	return v
+}
+
+// Summarize returns copy of v with arrays longer than items cut to their
+// first items elements followed by a note with the number of omitted
+// elements. Items less than 1 keeps the arrays whole.
+func Summarize(v any, items int) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, x := range v {
+			m[k] = Summarize(x, items)
+		}
+		return m
+	case []any:
+		n := len(v)
+		if items > 0 && n > items {
+			n = items
+		}
+		list := make([]any, 0, n+1)
+		for _, x := range v[:n] {
+			list = append(list, Summarize(x, items))
+		}
+		if n < len(v) {
+			list = append(list, fmt.Sprintf("... %d more of %d items", len(v)-n, len(v)))
+		}
+		return list
+	}
+	return v
+}
+
+// Minify returns v encoded as compact JSON.
+func Minify(v any) (string, error) {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return "", errors.Wrap(err, "error encoding JSON")
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,94 @@
+package structured
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJSON = `{
+  "kind": "List",
+  "items": [
+    {"metadata": {"name": "a", "labels": {"app.kubernetes.io/name": "web"}}, "spec": {"replicas": 2}},
+    {"metadata": {"name": "b"}, "spec": {"replicas": 3}},
+    {"metadata": {"name": "c"}}
+  ]
+}`
+
+const testYAML = `kind: Deployment
+metadata:
+  name: web
+spec:
+  ports: [80, 443, 8080]
+---
+kind: Service
+metadata:
+  name: web
+`
+
+func TestSplitPath(t *testing.T) {
+	p, sel := SplitPath("state.json#.items[].metadata")
+	assert.Equal(t, "state.json", p)
+	assert.Equal(t, ".items[].metadata", sel)
+
+	p, sel = SplitPath("report.pdf#pages=1")
+	assert.Equal(t, "report.pdf#pages=1", p)
+	assert.Empty(t, sel)
+
+	assert.True(t, IsStructured("state.json#.items"))
+	assert.True(t, IsStructured("config.YML"))
+	assert.False(t, IsStructured("data.csv"))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	js := filepath.Join(dir, "state.json")
+	assert.NoError(t, os.WriteFile(js, []byte(testJSON), 0o600))
+	ym := filepath.Join(dir, "manifest.yaml")
+	assert.NoError(t, os.WriteFile(ym, []byte(testYAML), 0o600))
+
+	tests := []struct {
+		name     string
+		path     string
+		selector string
+		items    int
+		want     string
+	}{
+		{"all", js, "", 0, `{"items":[{"metadata":{"labels":{"app.kubernetes.io/name":"web"},"name":"a"},"spec":{"replicas":2}},{"metadata":{"name":"b"},"spec":{"replicas":3}},{"metadata":{"name":"c"}}],"kind":"List"}`},
+		{"iterate", js, ".items[].metadata.name", 0, `["a","b","c"]`},
+		{"missing in iteration", js, ".items[].spec.replicas", 0, `[2,3]`},
+		{"index", js, ".items[-1].metadata", 0, `{"name":"c"}`},
+		{"quoted key", js, `.items[0].metadata.labels."app.kubernetes.io/name"`, 0, `"web"`},
+		{"bracket key", js, `.items[0]["spec"]`, 0, `{"replicas":2}`},
+		{"slice", js, ".items[1:].metadata.name", 0, `["b","c"]`},
+		{"summarized", js, ".items[].metadata.name", 2, `["a","b","... 1 more of 3 items"]`},
+		{"yaml documents", ym, ".[].kind", 0, `["Deployment","Service"]`},
+		{"yaml summarized", ym, ".[0].spec", 1, `{"ports":[80,"... 2 more of 3 items"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.path, tt.selector, tt.items)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, sel := range []string{".missing", ".items[5]", ".kind[0]", ".items[x]", "items", `."open`} {
+		_, err := Load(js, sel, 0)
+		assert.Error(t, err, sel)
+	}
+
+	_, err := Load(filepath.Join(dir, "missing.json"), "", 0)
+	assert.Error(t, err)
+
+	for _, doc := range []string{`{"a": 1} {"b": 2}`, `{"a": 1}]`, `[1] x`} {
+		_, err = Read([]byte(doc), false, "", 0)
+		assert.ErrorContains(t, err, "unexpected data after JSON value", doc)
+	}
+	got, err := Read([]byte("{\"a\": 1}\n\n"), false, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, got)
+}
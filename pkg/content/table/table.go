@@ -0,0 +1,330 @@
+package table
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Type is the data type of column inferred from its values.
+type Type string
+
+const (
+	Integer Type = "integer"
+	Float   Type = "float"
+	Date    Type = "date"
+	Bool    Type = "bool"
+	String  Type = "string"
+
+	// SampleDefault is the default number of rows included when the table
+	// doesn't fit into the budget.
+	SampleDefault = 20
+
+	maxDistinct = 1000
+)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006/01/02",
+	"01/02/2006",
+}
+
+// Column describes table column and the statistics of its values.
+type Column struct {
+	Name   string
+	Type   Type
+	Layout string // date layout for Date columns
+
+	Count    int // non-empty values
+	Empty    int
+	Distinct int // capped at 1000
+	Min      float64
+	Max      float64
+	Mean     float64
+	MinDate  time.Time
+	MaxDate  time.Time
+}
+
+// Table is CSV data with inferred column types.
+type Table struct {
+	Name    string
+	Columns []*Column
+	Rows    [][]string
+}
+
+// IsTable reports whether the file at path is CSV or TSV based on its
+// extension.
+func IsTable(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".csv" || ext == ".tsv"
+}
+
+// Load reads the CSV (or TSV) file at path.
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening file: %s", path)
+	}
+	defer f.Close()
+
+	comma := ','
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		comma = '\t'
+	}
+
+	t, err := Read(filepath.Base(path), f, comma)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading table: %s", path)
+	}
+	return t, nil
+}
+
+// Read parses CSV data from r. The first record is used as header.
+func Read(name string, r io.Reader, comma rune) (*Table, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing CSV")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no data")
+	}
+
+	t := &Table{Name: name}
+	for _, h := range records[0] {
+		t.Columns = append(t.Columns, &Column{Name: strings.TrimSpace(h)})
+	}
+
+	for _, rec := range records[1:] {
+		row := make([]string, len(t.Columns))
+		for i := range row {
+			if i < len(rec) {
+				row[i] = strings.TrimSpace(rec[i])
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+
+	for i, c := range t.Columns {
+		c.analyze(t.Rows, i)
+	}
+
+	return t, nil
+}
+
+// Index returns the index of the named column (case insensitive), or -1.
+func (t *Table) Index(name string) int {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// analyze infers the column type and computes its statistics.
+func (c *Column) analyze(rows [][]string, i int) {
+	values := make([]string, 0, len(rows))
+	distinct := map[string]bool{}
+	for _, r := range rows {
+		v := r[i]
+		if v == "" {
+			c.Empty++
+			continue
+		}
+		values = append(values, v)
+		if len(distinct) < maxDistinct {
+			distinct[v] = true
+		}
+	}
+	c.Count = len(values)
+	c.Distinct = len(distinct)
+	c.Type, c.Layout = inferType(values)
+
+	switch c.Type {
+	case Integer, Float:
+		c.Min, c.Max = math.Inf(1), math.Inf(-1)
+		var sum float64
+		for _, v := range values {
+			f, _ := strconv.ParseFloat(v, 64)
+			c.Min = math.Min(c.Min, f)
+			c.Max = math.Max(c.Max, f)
+			sum += f
+		}
+		c.Mean = sum / float64(len(values))
+	case Date:
+		for _, v := range values {
+			d, _ := time.Parse(c.Layout, v)
+			if c.MinDate.IsZero() || d.Before(c.MinDate) {
+				c.MinDate = d
+			}
+			if d.After(c.MaxDate) {
+				c.MaxDate = d
+			}
+		}
+	}
+}
+
+func inferType(values []string) (Type, string) {
+	if len(values) == 0 {
+		return String, ""
+	}
+
+	all := func(f func(string) bool) bool {
+		for _, v := range values {
+			if !f(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(func(v string) bool { _, err := strconv.ParseInt(v, 10, 64); return err == nil }):
+		return Integer, ""
+	case all(func(v string) bool { _, err := strconv.ParseFloat(v, 64); return err == nil }):
+		return Float, ""
+	case all(func(v string) bool { _, err := strconv.ParseBool(v); return err == nil }):
+		return Bool, ""
+	}
+
+	for _, layout := range dateLayouts {
+		if all(func(v string) bool { _, err := time.Parse(layout, v); return err == nil }) {
+			return Date, layout
+		}
+	}
+
+	return String, ""
+}
+
+// Float returns the numeric value of v in this column.
+func (c *Column) Float(v string) (float64, bool) {
+	switch c.Type {
+	case Integer, Float:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	case Date:
+		d, err := time.Parse(c.Layout, v)
+		return float64(d.Unix()), err == nil
+	}
+	return 0, false
+}
+
+// String returns the description of column and its statistics.
+func (c *Column) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%s): %d values", c.Name, c.Type, c.Count)
+	if c.Empty > 0 {
+		fmt.Fprintf(&sb, ", %d empty", c.Empty)
+	}
+
+	switch c.Type {
+	case Integer, Float:
+		fmt.Fprintf(&sb, ", min %s, max %s, mean %s", formatFloat(c.Min), formatFloat(c.Max), formatFloat(c.Mean))
+	case Date:
+		fmt.Fprintf(&sb, ", from %s to %s", c.MinDate.Format(c.Layout), c.MaxDate.Format(c.Layout))
+	default:
+		if c.Distinct >= maxDistinct {
+			fmt.Fprintf(&sb, ", over %d distinct", maxDistinct)
+		} else {
+			fmt.Fprintf(&sb, ", %d distinct", c.Distinct)
+		}
+	}
+
+	return sb.String()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Summary returns the table schema and the column statistics.
+func (t *Table) Summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Table %s (%d rows, %d columns):\n", t.Name, len(t.Rows), len(t.Columns))
+	for _, c := range t.Columns {
+		fmt.Fprintf(&sb, "- %s\n", c)
+	}
+	return sb.String()
+}
+
+// Content returns the summary followed by the table data as CSV. When all
+// rows don't fit into the budget (in bytes), only evenly spaced sample
+// of rows is included.
+func (t *Table) Content(sample, budget int) (string, error) {
+	if sample <= 0 {
+		sample = SampleDefault
+	}
+
+	var sb strings.Builder
+	sb.WriteString(t.Summary())
+
+	all, err := t.csv(t.Rows)
+	if err != nil {
+		return "", err
+	}
+
+	if budget <= 0 || sb.Len()+len(all) <= budget {
+		sb.WriteString("Data:\n")
+		sb.WriteString(all)
+		return sb.String(), nil
+	}
+
+	rows := t.sample(sample)
+	data, err := t.csv(rows)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&sb, "Sample data (%d of %d rows):\n", len(rows), len(t.Rows))
+	sb.WriteString(data)
+
+	return sb.String(), nil
+}
+
+// sample returns n evenly spaced rows including the first and last one.
+func (t *Table) sample(n int) [][]string {
+	if n >= len(t.Rows) {
+		return t.Rows
+	}
+	if n == 1 {
+		return t.Rows[:1]
+	}
+	rows := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		rows = append(rows, t.Rows[i*(len(t.Rows)-1)/(n-1)])
+	}
+	return rows
+}
+
+func (t *Table) csv(rows [][]string) (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	if err := w.Write(header); err != nil {
+		return "", errors.Wrap(err, "error writing CSV")
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return "", errors.Wrap(err, "error writing CSV")
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,55 @@
+package table
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	_, err := Load("file-not-exists.csv")
+	assert.Error(t, err)
+
+	tb, err := Load("../../../content/monthly-gas-price.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "monthly-gas-price.csv", tb.Name)
+	assert.Len(t, tb.Rows, 284)
+	assert.Equal(t, Date, tb.Columns[0].Type)
+	assert.Equal(t, Float, tb.Columns[1].Type)
+	assert.Equal(t, "Year Month (date): 284 values, from 1997-01 to 2020-08", tb.Columns[0].String())
+
+	tb, err = Load("../../../content/annual-us-gdp.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, Integer, tb.Columns[0].Type)
+	assert.Equal(t, 1, tb.Index("LEVEL-CURRENT"))
+	assert.Equal(t, -1, tb.Index("missing"))
+}
+
+func TestRead(t *testing.T) {
+	data := "name,qty,price,active,day\na,1,1.5,true,2023-01-02\nb,,2.5,false,2023-03-04\nc,3,-1,true,2022-12-31\n"
+	tb, err := Read("test", strings.NewReader(data), ',')
+	assert.NoError(t, err)
+
+	assert.Equal(t, "name (string): 3 values, 3 distinct", tb.Columns[0].String())
+	assert.Equal(t, "qty (integer): 2 values, 1 empty, min 1, max 3, mean 2", tb.Columns[1].String())
+	assert.Equal(t, "price (float): 3 values, min -1, max 2.5, mean 1", tb.Columns[2].String())
+	assert.Equal(t, Bool, tb.Columns[3].Type)
+	assert.Equal(t, "day (date): 3 values, from 2022-12-31 to 2023-03-04", tb.Columns[4].String())
+
+	t.Run("Content fits", func(t *testing.T) {
+		c, err := tb.Content(0, 0)
+		assert.NoError(t, err)
+		assert.Contains(t, c, "Table test (3 rows, 5 columns):\n")
+		assert.Contains(t, c, "Data:\nname,qty,price,active,day\na,1,1.5,true,2023-01-02\n")
+	})
+
+	t.Run("Content sample", func(t *testing.T) {
+		c, err := tb.Content(2, 100)
+		assert.NoError(t, err)
+		assert.Contains(t, c, "Sample data (2 of 3 rows):\nname,qty,price,active,day\na,1,1.5,true,2023-01-02\nc,3,-1,true,2022-12-31\n")
+	})
+
+	_, err = Read("empty", strings.NewReader(""), ',')
+	assert.Error(t, err)
+}
@@ -0,0 +1,352 @@
+package url
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Auth are the credentials sent with requests to Host. Host can include
+// port (e.g. wiki.example.com:8443) or match subdomains (e.g.
+// *.example.com). Token is sent as bearer token in the Authorization
+// header, along with Headers. TokenCommand and HeaderCommands are commands
+// printing the token or the header value, so the secrets don't have to be
+// stored in the config file. The credentials are sent over plain HTTP only
+// when Insecure is set.
+type Auth struct {
+	Host           string            `yaml:"host"`
+	Token          string            `yaml:"token,omitempty"`
+	TokenCommand   string            `yaml:"token_command,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	HeaderCommands map[string]string `yaml:"header_commands,omitempty"`
+	Insecure       bool              `yaml:"insecure,omitempty"`
+
+	once    sync.Once
+	headers http.Header
+	err     error
+}
+
+// Credentials are used to authenticate requests. The credentials are
+// never included in errors.
+type Credentials struct {
+	auth  []*Auth
+	netrc []*netrcEntry
+	jar   http.CookieJar
+}
+
+// NewCredentials returns credentials using auth, the login and password
+// from netrc file for hosts without auth, and the cookies from Netscape
+// cookies.txt file. Empty netrc path defaults to $NETRC or ~/.netrc, which
+// is skipped when it doesn't exist, empty cookies path loads no cookies.
+func NewCredentials(auth []*Auth, netrcPath, cookiesPath string) (*Credentials, error) {
+	c := &Credentials{auth: auth}
+	for _, a := range auth {
+		if strings.TrimSpace(a.Host) == "" {
+			return nil, errors.New("auth host not set")
+		}
+	}
+
+	required := netrcPath != ""
+	if netrcPath == "" {
+		netrcPath = defaultNetrc()
+	}
+	if netrcPath != "" {
+		n, err := readNetrc(netrcPath)
+		if err != nil && (required || !errors.Is(err, os.ErrNotExist)) {
+			return nil, errors.Wrapf(err, "error reading netrc file: %s", netrcPath)
+		}
+		c.netrc = n
+	}
+
+	if cookiesPath != "" {
+		jar, err := readCookies(cookiesPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading cookies file: %s", cookiesPath)
+		}
+		c.jar = jar
+	}
+	return c, nil
+}
+
+func defaultNetrc() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// headers returns the credential headers for request to u. When explicit,
+// only the credentials set for the host are used, not the netrc default.
+// Over plain HTTP only the auth allowing it is used.
+func (c *Credentials) headers(u *neturl.URL, explicit bool) (http.Header, error) {
+	h := http.Header{}
+	if c == nil || u.User != nil {
+		// credentials in url are used as they are
+		return h, nil
+	}
+
+	a := c.find(u)
+	if !strings.EqualFold(u.Scheme, "https") && (a == nil || !a.Insecure) {
+		return h, nil
+	}
+
+	if a != nil {
+		ah, err := a.resolve()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range ah {
+			h[k] = v
+		}
+	} else if n := c.login(u.Hostname(), explicit); n != nil {
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(n.login+":"+n.password)))
+	}
+	return h, nil
+}
+
+// find returns the auth for the host of u, the exact host is preferred
+// over the wildcard one.
+func (c *Credentials) find(u *neturl.URL) *Auth {
+	host := strings.ToLower(u.Host)
+	name := strings.ToLower(u.Hostname())
+	var match *Auth
+	for _, a := range c.auth {
+		h := strings.ToLower(strings.TrimSpace(a.Host))
+		switch {
+		case h == host || h == name:
+			return a
+		case strings.HasPrefix(h, "*.") && strings.HasSuffix(name, h[1:]) && match == nil:
+			match = a
+		}
+	}
+	return match
+}
+
+// resolve returns the headers of the auth, running the commands once.
+func (a *Auth) resolve() (http.Header, error) {
+	a.once.Do(func() {
+		h := http.Header{}
+		for k, v := range a.Headers {
+			h.Set(k, v)
+		}
+		for k, cmd := range a.HeaderCommands {
+			v, err := runSecret(cmd)
+			if err != nil {
+				a.err = errors.Wrapf(err, "error running command for header %s of host %s", k, a.Host)
+				return
+			}
+			h.Set(k, v)
+		}
+
+		token := a.Token
+		if a.TokenCommand != "" {
+			v, err := runSecret(a.TokenCommand)
+			if err != nil {
+				a.err = errors.Wrapf(err, "error running token command of host %s", a.Host)
+				return
+			}
+			token = v
+		}
+		if token != "" {
+			h.Set("Authorization", "Bearer "+token)
+		}
+		a.headers = h
+	})
+	return a.headers, a.err
+}
+
+// runSecret returns the trimmed output of the command, which isn't
+// included in the error as it could contain the secret.
+func runSecret(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("command not set")
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", errors.Errorf("%s failed: %s", args[0], err)
+	}
+	v := strings.TrimSpace(string(out))
+	if v == "" {
+		return "", errors.Errorf("%s printed no value", args[0])
+	}
+	return v, nil
+}
+
+// authorize sets the credential headers and cookies on the request and
+// returns the set headers. The headers set for the previous url are
+// removed first, so they aren't sent to other hosts when redirected. When
+// redirected from origin to other host, only the credentials set for that
+// host are sent.
+func (c *Credentials) authorize(req *http.Request, prev http.Header, origin *neturl.URL) (http.Header, error) {
+	for k := range prev {
+		req.Header.Del(k)
+	}
+
+	explicit := origin != nil && !strings.EqualFold(origin.Host, req.URL.Host)
+	h, err := c.headers(req.URL, explicit)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil && c.jar != nil {
+		for _, ck := range c.jar.Cookies(req.URL) {
+			h.Add("Cookie", ck.String())
+		}
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	return h, nil
+}
+
+type netrcEntry struct {
+	machine  string // empty for default
+	login    string
+	password string
+}
+
+// login returns the netrc entry for host, or the default one unless
+// explicit.
+func (c *Credentials) login(host string, explicit bool) *netrcEntry {
+	var def *netrcEntry
+	for _, n := range c.netrc {
+		if n.machine == "" && def == nil && !explicit {
+			def = n
+		}
+		if strings.EqualFold(n.machine, host) {
+			return n
+		}
+	}
+	return def
+}
+
+// readNetrc parses the netrc file, macros are skipped.
+func readNetrc(path string) ([]*netrcEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*netrcEntry
+	var n *netrcEntry
+	lines := strings.Split(string(b), "\n")
+	for i := 0; i < len(lines); i++ {
+		f := strings.Fields(lines[i])
+		for j := 0; j < len(f); j++ {
+			if strings.HasPrefix(f[j], "#") {
+				break
+			}
+			next := func() string {
+				if j+1 < len(f) {
+					j++
+					return f[j]
+				}
+				return ""
+			}
+			switch f[j] {
+			case "machine":
+				n = &netrcEntry{machine: next()}
+				list = append(list, n)
+			case "default":
+				n = &netrcEntry{}
+				list = append(list, n)
+			case "login":
+				if v := next(); n != nil {
+					n.login = v
+				}
+			case "password":
+				if v := next(); n != nil {
+					n.password = v
+				}
+			case "account":
+				next()
+			case "macdef":
+				// macro ends with empty line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(f)
+			}
+		}
+	}
+	return list, nil
+}
+
+// readCookies loads cookies from Netscape cookies.txt file (as exported
+// by browsers or used by curl).
+func readCookies(path string) (http.CookieJar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating cookie jar")
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// domain, subdomains, path, secure, expires, name, value
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			continue
+		}
+		domain := strings.TrimPrefix(f[0], ".")
+		ck := &http.Cookie{
+			Name:     f[5],
+			Value:    f[6],
+			Path:     f[2],
+			Secure:   strings.EqualFold(f[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(f[1], "TRUE") {
+			ck.Domain = domain
+		}
+		if exp, err := strconv.ParseInt(f[4], 10, 64); err == nil && exp > 0 {
+			ck.Expires = time.Unix(exp, 0)
+		}
+
+		scheme := "http"
+		if ck.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&neturl.URL{Scheme: scheme, Host: domain, Path: f[2]}, []*http.Cookie{ck})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return jar, nil
+}
+
+// Redact returns the url without password, so it can be printed.
+func Redact(u string) string {
+	pu, err := neturl.Parse(u)
+	if err != nil || pu.User == nil {
+		return u
+	}
+	return pu.Redacted()
+}
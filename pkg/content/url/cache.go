@@ -0,0 +1,190 @@
+package url
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	cacheDirName = "aictl/http"
+	metaExt      = ".json"
+	bodyExt      = ".body"
+)
+
+// Cache stores the downloaded content on disk, keyed by hash of the url.
+type Cache struct {
+	Dir string
+}
+
+// CacheEntry describes cached response. Texts holds the content extracted
+// from the body, keyed by the loading options, so it doesn't have to be
+// extracted again while the body doesn't change. The urls are stored with
+// the credentials redacted (see Redact).
+type CacheEntry struct {
+	URL          string            `json:"url"`
+	FinalURL     string            `json:"final_url,omitempty"`
+	ContentType  string            `json:"content_type,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	LastModified string            `json:"last_modified,omitempty"`
+	Fetched      time.Time         `json:"fetched"`
+	Validated    time.Time         `json:"validated"`
+	Size         int64             `json:"size"`
+	Texts        map[string]string `json:"texts,omitempty"`
+
+	// hash of the complete url, the entries without it are keyed by URL
+	id string
+}
+
+// DefaultCache returns cache in the user cache directory
+// (e.g. ~/.cache/aictl/http on Linux).
+func DefaultCache() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding user cache directory")
+	}
+	return &Cache{Dir: filepath.Join(dir, cacheDirName)}, nil
+}
+
+func cacheID(url string) string {
+	h := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(h[:])
+}
+
+// path returns the path of the entry files without extension.
+func (c *Cache) path(e *CacheEntry) string {
+	if e.id == "" {
+		return filepath.Join(c.Dir, cacheID(e.URL))
+	}
+	return filepath.Join(c.Dir, e.id)
+}
+
+// Get returns the cached entry and body for url, or nil when the url
+// isn't cached.
+func (c *Cache) Get(url string) (*CacheEntry, []byte, error) {
+	id := cacheID(url)
+	k := filepath.Join(c.Dir, id)
+	b, err := os.ReadFile(k + metaExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error reading cache: %s", Redact(url))
+	}
+
+	e := CacheEntry{id: id}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, nil, errors.Wrapf(err, "error parsing cache entry: %s", Redact(url))
+	}
+
+	body, err := os.ReadFile(k + bodyExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error reading cache: %s", Redact(url))
+	}
+	return &e, body, nil
+}
+
+// Put stores the entry along with the body, nil body updates only the
+// entry.
+func (c *Cache) Put(e *CacheEntry, body []byte) error {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return errors.Wrapf(err, "error creating cache directory: %s", c.Dir)
+	}
+
+	k := c.path(e)
+	if body != nil {
+		if err := writeFile(k+bodyExt, body); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding cache entry")
+	}
+	return writeFile(k+metaExt, b)
+}
+
+// writeFile writes the file atomically, so concurrent readers never see
+// partial content.
+func writeFile(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return errors.Wrap(err, "error writing cache")
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return errors.Wrap(err, "error writing cache")
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "error writing cache")
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "error writing cache")
+	}
+	return nil
+}
+
+// List returns the cached entries sorted by url.
+func (c *Cache) List() ([]*CacheEntry, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*"+metaExt))
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing cache")
+	}
+
+	var list []*CacheEntry
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		e := CacheEntry{id: strings.TrimSuffix(filepath.Base(f), metaExt)}
+		if err := json.Unmarshal(b, &e); err != nil {
+			continue
+		}
+		list = append(list, &e)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	return list, nil
+}
+
+// Purge removes the entries with url starting with prefix, or all of them
+// when prefix is empty, and returns the number of removed entries.
+func (c *Cache) Purge(prefix string) (int, error) {
+	list, err := c.List()
+	if err != nil {
+		return 0, err
+	}
+
+	// the urls are stored redacted
+	prefix = Redact(prefix)
+
+	n := 0
+	for _, e := range list {
+		if !strings.HasPrefix(e.URL, prefix) {
+			continue
+		}
+		k := c.path(e)
+		for _, f := range []string{k + metaExt, k + bodyExt} {
+			if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return n, errors.Wrapf(err, "error removing cache entry: %s", e.URL)
+			}
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,130 @@
+package url
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+
+	e, body, err := c.Get("https://example.com/a")
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+	assert.Nil(t, body)
+
+	for _, u := range []string{"https://example.com/a", "https://example.com/b", "https://other.com/"} {
+		assert.NoError(t, c.Put(&CacheEntry{URL: u, ETag: `"1"`, Size: 4}, []byte("body")))
+	}
+
+	e, body, err = c.Get("https://example.com/a")
+	assert.NoError(t, err)
+	assert.Equal(t, `"1"`, e.ETag)
+	assert.Equal(t, "body", string(body))
+
+	// metadata only
+	e.Texts = map[string]string{"HTML:main": "text"}
+	assert.NoError(t, c.Put(e, nil))
+	e, body, err = c.Get("https://example.com/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "text", e.Texts["HTML:main"])
+	assert.Equal(t, "body", string(body))
+
+	list, err := c.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 3)
+	assert.Equal(t, "https://example.com/a", list[0].URL)
+
+	n, err := c.Purge("https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	list, err = c.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	n, err = c.Purge("")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestGetCached(t *testing.T) {
+	var requests, modified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&modified, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "web"}`))
+	}))
+	defer srv.Close()
+
+	opt := Options{Cache: &Cache{Dir: t.TempDir()}}
+
+	p, err := Get("desc", srv.URL+"/config.json", opt)
+	assert.NoError(t, err)
+	assert.False(t, p.Cached)
+
+	p, err = Get("desc", srv.URL+"/config.json", opt)
+	assert.NoError(t, err)
+	assert.True(t, p.Cached)
+	txt, err := content.JoinText(p.Parts)
+	assert.NoError(t, err)
+	assert.Equal(t, "desc\n{\"name\":\"web\"}\n", txt)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&modified))
+
+	t.Run("Offline", func(t *testing.T) {
+		opt.Offline = true
+		p, err := Get("desc", srv.URL+"/config.json", opt)
+		assert.NoError(t, err)
+		assert.True(t, p.Cached)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+		_, err = Get("desc", srv.URL+"/other.json", opt)
+		assert.ErrorContains(t, err, "offline")
+	})
+
+	t.Run("Credentials", func(t *testing.T) {
+		opt := Options{Cache: &Cache{Dir: t.TempDir()}}
+		u := strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + "/config.json"
+		_, err := Get("desc", u, opt)
+		assert.NoError(t, err)
+
+		list, err := opt.Cache.List()
+		assert.NoError(t, err)
+		assert.Len(t, list, 1)
+		assert.Equal(t, Redact(u), list[0].URL)
+		files, err := filepath.Glob(filepath.Join(opt.Cache.Dir, "*"))
+		assert.NoError(t, err)
+		for _, f := range files {
+			b, err := os.ReadFile(f)
+			assert.NoError(t, err)
+			assert.NotContains(t, string(b), "secret")
+		}
+
+		// keyed by the complete url
+		p, err := Get("desc", u, opt)
+		assert.NoError(t, err)
+		assert.True(t, p.Cached)
+		e, _, err := opt.Cache.Get(Redact(u))
+		assert.NoError(t, err)
+		assert.Nil(t, e)
+
+		n, err := opt.Cache.Purge(u)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+}
@@ -0,0 +1,407 @@
+package url
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"mime"
+	neturl "net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k3a/html2text"
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/content/article"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// CrawlDepthDefault is the default link depth followed from the
+	// start page.
+	CrawlDepthDefault = 2
+	// CrawlPagesDefault is the default maximum number of crawled pages.
+	CrawlPagesDefault = 20
+
+	crawlArg           = "crawl"
+	crawlConcurrency   = 4
+	crawlDelay         = 250 * time.Millisecond
+	crawlMaxPagesLimit = 500
+)
+
+// CrawlOptions configures crawling of the site.
+type CrawlOptions struct {
+	// Depth is the link depth followed from the start page.
+	Depth int
+	// MaxPages is the maximum number of loaded pages.
+	MaxPages int
+	// Prefix limits the crawled pages to urls with the path prefix,
+	// defaults to the directory of the start url.
+	Prefix string
+	// Concurrency is the number of pages fetched in parallel.
+	Concurrency int
+	// Delay is the minimum delay between requests.
+	Delay time.Duration
+}
+
+// CrawledPage is single page loaded during crawl.
+type CrawledPage struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+// ParseCrawl parses the crawl arguments following url
+// (e.g. "crawl depth=3 pages=50 prefix=/docs/ delay=1s"). Returns nil
+// options when the arguments don't start with crawl.
+func ParseCrawl(args string) (*CrawlOptions, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] != crawlArg {
+		return nil, nil
+	}
+
+	opt := &CrawlOptions{
+		Depth:       CrawlDepthDefault,
+		MaxPages:    CrawlPagesDefault,
+		Concurrency: crawlConcurrency,
+		Delay:       crawlDelay,
+	}
+	for _, f := range fields[1:] {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, errors.Errorf("invalid crawl option: %s", f)
+		}
+		var err error
+		switch k {
+		case "depth":
+			opt.Depth, err = strconv.Atoi(v)
+			if err == nil && opt.Depth < 0 {
+				err = errors.New("negative depth")
+			}
+		case "pages":
+			opt.MaxPages, err = strconv.Atoi(v)
+			if err == nil && (opt.MaxPages < 1 || opt.MaxPages > crawlMaxPagesLimit) {
+				err = errors.Errorf("expected number between 1 and %d", crawlMaxPagesLimit)
+			}
+		case "prefix":
+			opt.Prefix = v
+		case "delay":
+			opt.Delay, err = time.ParseDuration(v)
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid crawl option: %s", f)
+		}
+	}
+	return opt, nil
+}
+
+// Crawl loads the pages of the site linked from the start url, breadth
+// first up to the configured depth and number of pages. Only pages on
+// the same host with the path prefix are crawled, respecting robots.txt,
+// and the pages listed in the site map are crawled along with the links
+// of the start page. Pages with the same content are loaded only once.
+// The main content of each page is extracted (see Get) and all pages are
+// returned as single text part, each page labeled with its title and url.
+// Credentials in the start url are sent with the requests to the site,
+// but they aren't part of the page urls.
+func Crawl(ctx context.Context, desc, start string, opt CrawlOptions, fetch Options) (*Page, error) {
+	base, err := neturl.Parse(start)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, errors.Errorf("invalid url %s", Redact(start))
+	}
+	user := base.User
+	base = normalize(base)
+
+	if opt.Prefix == "" {
+		opt.Prefix = base.Path
+		if !strings.HasSuffix(opt.Prefix, "/") {
+			opt.Prefix = path.Dir(opt.Prefix)
+			if !strings.HasSuffix(opt.Prefix, "/") {
+				opt.Prefix += "/"
+			}
+		}
+	}
+	if opt.MaxPages <= 0 {
+		opt.MaxPages = CrawlPagesDefault
+	}
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = crawlConcurrency
+	}
+
+	c := &crawler{
+		base:   base,
+		user:   user,
+		opt:    opt,
+		fetch:  fetch,
+		seen:   map[string]bool{base.String(): true},
+		hashes: map[[32]byte]bool{},
+	}
+	c.robots = c.getRobots(ctx, base.Scheme+"://"+base.Host)
+
+	if !c.robots.allowed(base.Path) {
+		return nil, errors.Errorf("url disallowed by robots.txt: %s", Redact(start))
+	}
+
+	// site map is looked up at the default location when robots.txt
+	// doesn't list any
+	sitemaps := c.robots.sitemaps
+	if len(sitemaps) == 0 {
+		sitemaps = []string{base.Scheme + "://" + base.Host + "/sitemap.xml"}
+	}
+	var listed []string
+	for _, s := range sitemaps {
+		listed = append(listed, c.scope(c.getSitemap(ctx, s, true))...)
+	}
+
+	level := []string{base.String()}
+
+	var pages []*CrawledPage
+	for depth := 0; len(level) > 0 && len(pages) < opt.MaxPages; depth++ {
+		if n := opt.MaxPages - len(pages); len(level) > n {
+			level = level[:n]
+		}
+
+		results := c.fetchAll(ctx, level)
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "crawl canceled")
+		}
+
+		var next []string
+		if depth == 0 && opt.Depth > 0 {
+			next = listed
+		}
+		for _, r := range results {
+			if r.err != nil {
+				continue
+			}
+			h := sha256.Sum256([]byte(r.page.Text))
+			if c.hashes[h] {
+				continue
+			}
+			c.hashes[h] = true
+			pages = append(pages, r.page)
+			if depth < opt.Depth {
+				next = append(next, c.scope(r.links)...)
+			}
+		}
+		level = next
+	}
+
+	if len(pages) == 0 {
+		return nil, errors.Errorf("no pages loaded from %s", Redact(start))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(desc)
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "Content of %d pages from %s:\n", len(pages), Redact(start))
+	for i, p := range pages {
+		title := p.Title
+		if title == "" {
+			title = Redact(p.URL)
+		}
+		fmt.Fprintf(&sb, "\n=== [%d/%d] %s (%s) ===\n%s\n", i+1, len(pages), title, Redact(p.URL), strings.TrimSpace(p.Text))
+	}
+
+	return &Page{
+		Type:    HTML,
+		Article: !fetch.FullPage,
+		Parts:   []content.Part{content.Text(sb.String())},
+		Pages:   pages,
+	}, nil
+}
+
+type crawler struct {
+	base   *neturl.URL
+	user   *neturl.Userinfo // of the start url
+	opt    CrawlOptions
+	fetch  Options
+	robots *robots
+
+	seen   map[string]bool
+	hashes map[[32]byte]bool
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next request
+}
+
+type result struct {
+	page  *CrawledPage
+	links []string
+	err   error
+}
+
+// fetchAll fetches the pages concurrently, returning the results in the
+// order of urls.
+func (c *crawler) fetchAll(ctx context.Context, urls []string) []*result {
+	results := make([]*result, len(urls))
+	sem := make(chan struct{}, c.opt.Concurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := c.wait(ctx); err != nil {
+				results[i] = &result{err: err}
+				return
+			}
+			results[i] = c.fetchPage(ctx, u)
+		}(i, u)
+	}
+	wg.Wait()
+	return results
+}
+
+// wait blocks until the rate limit allows next request.
+func (c *crawler) wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	at := now
+	if c.next.After(now) {
+		at = c.next
+	}
+	c.next = at.Add(c.opt.Delay)
+	c.mu.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return nil
+}
+
+func (c *crawler) fetchPage(ctx context.Context, u string) *result {
+	r, err := fetch(ctx, c.authorized(u), c.fetch)
+	if err != nil {
+		return &result{err: err}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return &result{err: errors.Errorf("not HTML %s: %s", u, mediaType)}
+	}
+	// redirected outside of the crawled site
+	final, err := neturl.Parse(r.url)
+	if err != nil || final.Host != c.base.Host {
+		return &result{err: errors.Errorf("redirected outside of site %s", u)}
+	}
+	// the links don't inherit the credentials
+	final.User = nil
+	body := r.body
+
+	page := &CrawledPage{URL: u}
+	if !c.fetch.FullPage {
+		if a, err := article.Extract(body, final.String()); err == nil {
+			page.Title = a.Title
+			page.Text = a.String()
+		}
+	}
+	if page.Text == "" {
+		page.Text = html2text.HTML2Text(string(body))
+	}
+
+	return &result{page: page, links: links(body, final)}
+}
+
+// authorized returns url on the crawled site with the credentials of the
+// start url, which are kept out of the page urls.
+func (c *crawler) authorized(u string) string {
+	if c.user == nil {
+		return u
+	}
+	pu, err := neturl.Parse(u)
+	if err != nil || pu.Host != c.base.Host || pu.User != nil {
+		return u
+	}
+	pu.User = c.user
+	return pu.String()
+}
+
+// scope returns the urls which should be crawled: not seen yet, on the
+// same site with the path prefix, and allowed by robots.txt. The returned
+// urls are marked as seen.
+func (c *crawler) scope(urls []string) []string {
+	var list []string
+	for _, s := range urls {
+		u, err := neturl.Parse(s)
+		if err != nil {
+			continue
+		}
+		u = normalize(u)
+		key := u.String()
+		if c.seen[key] || u.Scheme != c.base.Scheme || u.Host != c.base.Host ||
+			!strings.HasPrefix(u.Path, c.opt.Prefix) || !c.robots.allowed(u.Path) {
+			continue
+		}
+		c.seen[key] = true
+		list = append(list, key)
+	}
+	return list
+}
+
+// links returns the absolute urls of links in the page.
+func links(body []byte, base *neturl.URL) []string {
+	var list []string
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return list
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			if a != atom.A && a != atom.Base || !hasAttr {
+				continue
+			}
+			for {
+				k, v, more := z.TagAttr()
+				if string(k) == "href" {
+					ref, err := neturl.Parse(strings.TrimSpace(string(v)))
+					if err == nil {
+						if a == atom.Base {
+							base = base.ResolveReference(ref)
+						} else {
+							list = append(list, base.ResolveReference(ref).String())
+						}
+					}
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+}
+
+// normalize returns url without fragment and user info, with lower case
+// scheme and host, without the default port, and with root path when
+// empty.
+func normalize(u *neturl.URL) *neturl.URL {
+	n := *u
+	n.User = nil
+	n.Fragment = ""
+	n.RawFragment = ""
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	if (n.Scheme == "http" && strings.HasSuffix(n.Host, ":80")) || (n.Scheme == "https" && strings.HasSuffix(n.Host, ":443")) {
+		n.Host = n.Host[:strings.LastIndex(n.Host, ":")]
+	}
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	return &n
+}
@@ -0,0 +1,227 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/stretchr/testify/assert"
+)
+
+func docPage(title string, links ...string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<html><head><title>%s</title></head><body><nav>", title)
+	for _, l := range links {
+		fmt.Fprintf(&sb, `<a href="%s">%s</a> `, l, l)
+	}
+	fmt.Fprintf(&sb, "</nav><main><h1>%s</h1><p>%s</p></main></body></html>", title,
+		strings.Repeat("This page documents "+title+", with examples and details. ", 8))
+	return sb.String()
+}
+
+func TestCrawl(t *testing.T) {
+	var mu sync.Mutex
+	requested := map[string]int{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /docs/private\n")
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset><url><loc>http://%s/docs/listed</loc></url><url><loc>http://%s/blog/post</loc></url></urlset>`, r.Host, r.Host)
+	})
+	pages := map[string]string{
+		"/docs/":        docPage("Intro", "install", "/docs/usage#top", "/docs/private/keys", "/blog/", "https://example.com/docs/", "/docs/intro-copy"),
+		"/docs/install": docPage("Install", "/docs/deep"),
+		"/docs/usage":   docPage("Usage", "/docs/"),
+		"/docs/listed":  docPage("Listed"),
+		"/docs/deep":    docPage("Deep", "/docs/deeper"),
+		"/docs/deeper":  docPage("Deeper"),
+	}
+	pages["/docs/intro-copy"] = pages["/docs/"]
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		mu.Unlock()
+		p, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, p)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	opt, err := ParseCrawl("crawl depth=2 pages=10 delay=1ms")
+	assert.NoError(t, err)
+
+	p, err := Crawl(context.Background(), "desc", srv.URL+"/docs/", *opt, Options{})
+	assert.NoError(t, err)
+
+	var urls []string
+	for _, cp := range p.Pages {
+		urls = append(urls, strings.TrimPrefix(cp.URL, srv.URL))
+	}
+	// breadth first, sitemap pages with the start page links, duplicate
+	// content, other paths, and disallowed pages skipped
+	assert.Equal(t, []string{"/docs/", "/docs/listed", "/docs/install", "/docs/usage", "/docs/deep"}, urls)
+	assert.Equal(t, "5 HTML pages", p.String())
+	assert.Zero(t, requested["/docs/private/keys"])
+	assert.Zero(t, requested["/blog/post"])
+	assert.Zero(t, requested["/docs/deeper"])
+	for path, n := range requested {
+		assert.Equal(t, 1, n, path)
+	}
+
+	txt, err := content.JoinText(p.Parts)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(txt, "desc\nContent of 5 pages from "+srv.URL+"/docs/:\n\n=== [1/5] Intro ("+srv.URL+"/docs/) ===\nTitle: Intro\n"))
+
+	t.Run("Max pages", func(t *testing.T) {
+		opt, err := ParseCrawl("crawl pages=2 delay=1ms")
+		assert.NoError(t, err)
+		p, err := Crawl(context.Background(), "desc", srv.URL+"/docs/", *opt, Options{})
+		assert.NoError(t, err)
+		assert.Len(t, p.Pages, 2)
+	})
+
+	t.Run("Disallowed", func(t *testing.T) {
+		_, err := Crawl(context.Background(), "desc", srv.URL+"/docs/private/keys", *opt, Options{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Crawl(ctx, "desc", srv.URL+"/docs/", CrawlOptions{Delay: time.Second}, Options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestCrawlCredentials(t *testing.T) {
+	var mu sync.Mutex
+	unauthorized := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "secret" {
+			mu.Lock()
+			unauthorized++
+			mu.Unlock()
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, docPage("Intro", "install"))
+		case "/docs/install":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, docPage("Install"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	start := strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + "/docs/"
+	p, err := Crawl(context.Background(), "desc", start, CrawlOptions{Depth: 1, Delay: time.Millisecond}, Options{})
+	assert.NoError(t, err)
+	assert.Zero(t, unauthorized)
+
+	var urls []string
+	for _, cp := range p.Pages {
+		urls = append(urls, cp.URL)
+	}
+	assert.Equal(t, []string{srv.URL + "/docs/", srv.URL + "/docs/install"}, urls)
+
+	txt, err := content.JoinText(p.Parts)
+	assert.NoError(t, err)
+	assert.NotContains(t, txt, "secret")
+	assert.Contains(t, txt, "Content of 2 pages from "+Redact(start)+":\n")
+}
+
+func TestCrawlRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "Sitemap: http://%s/index.xml\n", r.Host)
+		case "/index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>http://%s/sitemap.xml</loc></sitemap></sitemapindex>`, r.Host)
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>http://%s/docs/listed</loc></url></urlset>`, r.Host)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, docPage(r.URL.Path))
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	delay := 100 * time.Millisecond
+	_, err := Crawl(context.Background(), "desc", srv.URL+"/docs/", CrawlOptions{Depth: 1, Delay: delay}, Options{})
+	assert.NoError(t, err)
+
+	// robots.txt, both sitemaps, and the pages are all rate limited
+	assert.Len(t, times, 5)
+	for i := 1; i < len(times); i++ {
+		assert.Greater(t, times[i].Sub(times[i-1]), delay/2, i)
+	}
+}
+
+func TestParseCrawl(t *testing.T) {
+	opt, err := ParseCrawl("")
+	assert.NoError(t, err)
+	assert.Nil(t, opt)
+
+	opt, err = ParseCrawl("crawl")
+	assert.NoError(t, err)
+	assert.Equal(t, CrawlDepthDefault, opt.Depth)
+	assert.Equal(t, CrawlPagesDefault, opt.MaxPages)
+
+	opt, err = ParseCrawl("crawl depth=0 pages=5 prefix=/guide/ delay=2s")
+	assert.NoError(t, err)
+	assert.Equal(t, &CrawlOptions{Depth: 0, MaxPages: 5, Prefix: "/guide/", Concurrency: crawlConcurrency, Delay: 2 * time.Second}, opt)
+
+	for _, args := range []string{"crawl depth", "crawl depth=-1", "crawl pages=0", "crawl pages=1000", "crawl speed=1"} {
+		_, err := ParseCrawl(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestRobots(t *testing.T) {
+	r := parseRobots(strings.NewReader(`# comment
+User-agent: googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow:
+
+Sitemap: https://example.com/sitemap.xml
+`))
+	assert.True(t, r.allowed("/docs/"))
+	assert.False(t, r.allowed("/private/keys"))
+	assert.True(t, r.allowed("/private/public/page"))
+	assert.False(t, r.allowed("/files/report.pdf"))
+	assert.True(t, r.allowed("/files/report.pdf.html"))
+	assert.Equal(t, []string{"https://example.com/sitemap.xml"}, r.sitemaps)
+
+	r = parseRobots(strings.NewReader("User-agent: aictl\nDisallow: /\n\nUser-agent: *\nAllow: /\n"))
+	assert.False(t, r.allowed("/docs/"))
+}
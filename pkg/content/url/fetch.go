@@ -0,0 +1,198 @@
+package url
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// response is the content downloaded from url, or loaded from cache.
+type response struct {
+	url         string // after redirects
+	contentType string
+	body        []byte
+
+	// entry is the cache entry, nil when cache is not used
+	entry *CacheEntry
+	// cached is set when the body wasn't downloaded
+	cached bool
+}
+
+// fetch downloads the content at url. With cache, the cached content is
+// revalidated using its ETag and Last-Modified date and downloaded again
+// only when it has changed. Offline, only cached content is returned.
+func fetch(ctx context.Context, url string, opt Options) (*response, error) {
+	var entry *CacheEntry
+	var cached []byte
+	if opt.Cache != nil {
+		var err error
+		if entry, cached, err = opt.Cache.Get(url); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Offline {
+		if entry == nil {
+			return nil, errors.Errorf("url not in cache %s (offline)", Redact(url))
+		}
+		return entry.response(cached, true), nil
+	}
+
+	g, err := newGuard(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRedirects := opt.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = MaxRedirectsDefault
+	}
+
+	// credential headers set on the last request
+	var auth http.Header
+
+	c := http.Client{
+		Timeout:   time.Duration(timeoutInSeconds) * time.Second,
+		Transport: reqTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return errors.Errorf("too many redirects %s: over %d", Redact(url), maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return errors.Errorf("invalid redirect %s: %s", Redact(url), req.URL.Redacted())
+			}
+			if err := g.check(req.Context(), req.URL); err != nil {
+				return err
+			}
+			auth, err = opt.Credentials.authorize(req, auth, via[0].URL)
+			return err
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating HTTP Get request")
+	}
+	if err := g.check(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	req = req.WithContext(withGuard(ctx, g))
+	if auth, err = opt.Credentials.authorize(req, nil, nil); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", clientAgent)
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error requesting %s", Redact(url))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.Validated = time.Now().UTC()
+		if err := opt.Cache.Put(entry, nil); err != nil {
+			return nil, err
+		}
+		return entry.response(cached, true), nil
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Errorf("url not found %s", Redact(url))
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errors.Errorf("access denied %s: %s, check the credentials of the host", Redact(url), resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("invalid response %s: %d - %s", Redact(url), resp.StatusCode, resp.Status)
+	}
+
+	maxSize := opt.MaxSize
+	if maxSize <= 0 {
+		maxSize = MaxSizeDefault
+	}
+	if resp.ContentLength > maxSize {
+		return nil, errors.Errorf("content too large %s: %d bytes over %d bytes limit", Redact(url), resp.ContentLength, maxSize)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading downloaded content from %s", Redact(url))
+	}
+	if int64(len(body)) > maxSize {
+		return nil, errors.Errorf("content too large %s: over %d bytes limit", Redact(url), maxSize)
+	}
+
+	r := &response{
+		url:         resp.Request.URL.String(),
+		contentType: resp.Header.Get("Content-Type"),
+		body:        body,
+	}
+
+	if opt.Cache != nil {
+		now := time.Now().UTC()
+		r.entry = &CacheEntry{
+			URL:          Redact(url),
+			FinalURL:     Redact(r.url),
+			ContentType:  r.contentType,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Fetched:      now,
+			Validated:    now,
+			Size:         int64(len(body)),
+			id:           cacheID(url),
+		}
+		if err := opt.Cache.Put(r.entry, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (e *CacheEntry) response(body []byte, cached bool) *response {
+	u := e.FinalURL
+	if u == "" {
+		u = e.URL
+	}
+	return &response{url: u, contentType: e.ContentType, body: body, entry: e, cached: cached}
+}
+
+// text returns the content extracted from the response body using
+// extract, reusing the one stored in cache for the same variant of
+// loading options.
+func (r *response) text(cache *Cache, variant string, extract func() (string, error)) (string, error) {
+	if r.entry != nil {
+		if txt, ok := r.entry.Texts[variant]; ok {
+			return txt, nil
+		}
+	}
+
+	txt, err := extract()
+	if err != nil {
+		return "", err
+	}
+
+	if r.entry != nil && cache != nil {
+		if r.entry.Texts == nil {
+			r.entry.Texts = map[string]string{}
+		}
+		r.entry.Texts[variant] = txt
+		if err := cache.Put(r.entry, nil); err != nil {
+			return "", err
+		}
+	}
+	return txt, nil
+}
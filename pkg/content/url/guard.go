@@ -0,0 +1,195 @@
+package url
+
+import (
+	"context"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// carrier-grade NAT range (RFC 6598), not covered by net.IP.IsPrivate
+var sharedRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+type guardKey struct{}
+
+// guard blocks requests to private, loopback and link-local addresses
+// (e.g. cloud metadata endpoints), except the allowed ones.
+type guard struct {
+	hosts  map[string]bool
+	ranges []*net.IPNet
+
+	mu sync.Mutex
+	// addresses of the proxies used by the requests, which resolve the
+	// hosts themselves
+	proxies map[string]bool
+}
+
+// newGuard returns guard for the options, or nil when private addresses
+// aren't blocked. Allowed entries are host names, IP addresses, or CIDR
+// ranges.
+func newGuard(opt Options) (*guard, error) {
+	if !opt.BlockPrivate {
+		return nil, nil
+	}
+	g := &guard{hosts: map[string]bool{}, proxies: map[string]bool{}}
+	for _, a := range opt.Allow {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		if strings.Contains(a, "/") {
+			_, r, err := net.ParseCIDR(a)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid allowed range %s", a)
+			}
+			g.ranges = append(g.ranges, r)
+			continue
+		}
+		if ip := net.ParseIP(a); ip != nil {
+			g.ranges = append(g.ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		g.hosts[a] = true
+	}
+	return g, nil
+}
+
+// blocked returns true when the ip is private and not allowed.
+func (g *guard) blocked(ip net.IP) bool {
+	if !isPrivate(ip) {
+		return false
+	}
+	for _, r := range g.ranges {
+		if r.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// check returns error when the host of url resolves to blocked address.
+func (g *guard) check(ctx context.Context, u *neturl.URL) error {
+	if g == nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	if g.hosts[host] {
+		return nil
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return errors.Errorf("error resolving host %s", host)
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if g.blocked(ip) {
+			return errors.Errorf("blocked request to private address %s (%s), allow the host to load it", u.Redacted(), ip)
+		}
+	}
+	return nil
+}
+
+// control checks the address of the connection just before it's made,
+// so the host can't resolve to different address after the check.
+func (g *guard) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid address %s", address)
+	}
+	if ip := net.ParseIP(host); ip != nil && g.blocked(ip) {
+		return errors.Errorf("blocked connection to private address %s", ip)
+	}
+	return nil
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() || sharedRange.Contains(ip)
+}
+
+// proxyFunc returns the proxy of the request, tests replace it since the
+// environment is read only once.
+var proxyFunc = http.ProxyFromEnvironment
+
+// proxy returns the proxy of the request (see http.ProxyFromEnvironment).
+// It's called for each request, including redirects, so the guard learns
+// which connections go through proxy.
+func proxy(req *http.Request) (*neturl.URL, error) {
+	p, err := proxyFunc(req)
+	if err != nil || p == nil {
+		return p, err
+	}
+	if g, ok := req.Context().Value(guardKey{}).(*guard); ok && g != nil {
+		g.mu.Lock()
+		g.proxies[proxyAddr(p)] = true
+		g.mu.Unlock()
+	}
+	return p, nil
+}
+
+// proxyAddr returns the host and port of the proxy as dialed by the
+// transport.
+func proxyAddr(p *neturl.URL) string {
+	port := p.Port()
+	if port == "" {
+		switch p.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(strings.ToLower(p.Hostname()), port)
+}
+
+// dial connects to the address, checking it using the guard in context
+// unless it's the address of proxy, which resolves the host itself.
+func dial(ctx context.Context, network, address string) (net.Conn, error) {
+	d := net.Dialer{Timeout: timeoutInSeconds * time.Second}
+	if g, ok := ctx.Value(guardKey{}).(*guard); ok && g != nil && !g.direct(address) {
+		d.Control = g.control
+	}
+	return d.DialContext(ctx, network, address)
+}
+
+// direct returns true when the connection to address doesn't have to be
+// checked: it's allowed host or proxy.
+func (g *guard) direct(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if g.hosts[strings.ToLower(host)] {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.proxies[strings.ToLower(address)]
+}
+
+// withGuard returns context with the guard checking the connections made
+// for the request and its redirects, except those to proxy (see proxy).
+func withGuard(ctx context.Context, g *guard) context.Context {
+	if g == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, guardKey{}, g)
+}
@@ -0,0 +1,121 @@
+package url
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/local":
+			// same server using its IP address
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "localhost", "127.0.0.1", 1)+"/", http.StatusFound)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("a", 2048)))
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	local := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	t.Run("Size", func(t *testing.T) {
+		_, err := Get("desc", srv.URL+"/large", Options{MaxSize: 1024})
+		assert.ErrorContains(t, err, "content too large")
+
+		_, err = Get("desc", srv.URL+"/large", Options{MaxSize: 4096})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Redirects", func(t *testing.T) {
+		_, err := Get("desc", srv.URL+"/loop", Options{MaxRedirects: 3})
+		assert.ErrorContains(t, err, "too many redirects")
+	})
+
+	t.Run("Private", func(t *testing.T) {
+		_, err := Get("desc", srv.URL+"/", Options{BlockPrivate: true})
+		assert.ErrorContains(t, err, "blocked request to private address")
+
+		_, err = Get("desc", srv.URL+"/", Options{BlockPrivate: true, Allow: []string{"127.0.0.0/8"}})
+		assert.NoError(t, err)
+
+		_, err = Get("desc", local+"/", Options{BlockPrivate: true, Allow: []string{"localhost"}})
+		assert.NoError(t, err)
+
+		// redirect to address which isn't allowed
+		_, err = Get("desc", local+"/local", Options{BlockPrivate: true, Allow: []string{"localhost"}})
+		assert.ErrorContains(t, err, "blocked request to private address")
+
+		_, err = Get("desc", srv.URL+"/", Options{BlockPrivate: true, Allow: []string{"bad/range"}})
+		assert.ErrorContains(t, err, "invalid allowed range")
+	})
+}
+
+func TestGuardProxyRedirect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+	// forward proxy which redirects to the private address
+	var proxiedRequests int32
+	proxied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxiedRequests, 1)
+		http.Redirect(w, r, target.URL+"/", http.StatusFound)
+	}))
+	defer proxied.Close()
+
+	proxyURL, err := neturl.Parse(proxied.URL)
+	assert.NoError(t, err)
+	old := proxyFunc
+	proxyFunc = func(req *http.Request) (*neturl.URL, error) {
+		if req.URL.Hostname() == "proxied.test" {
+			return proxyURL, nil
+		}
+		return nil, nil
+	}
+	defer func() { proxyFunc = old }()
+
+	g, err := newGuard(Options{BlockPrivate: true})
+	assert.NoError(t, err)
+	req, err := http.NewRequestWithContext(withGuard(context.Background(), g), http.MethodGet, "http://proxied.test/", nil)
+	assert.NoError(t, err)
+
+	// the redirect isn't checked by the client, so only the dial of the
+	// direct connection blocks it
+	c := http.Client{Transport: reqTransport}
+	_, err = c.Do(req)
+	assert.ErrorContains(t, err, "blocked connection to private address")
+	// the private address of the proxy isn't blocked
+	assert.Equal(t, int32(1), atomic.LoadInt32(&proxiedRequests))
+}
+
+func TestIsPrivate(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"192.168.0.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00:ec2::254":   true,
+		"fe80::1":         true,
+		"8.8.8.8":         false,
+		"2001:4860::8888": false,
+	}
+	for ip, want := range tests {
+		assert.Equal(t, want, isPrivate(net.ParseIP(ip)), ip)
+	}
+}
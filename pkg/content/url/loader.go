@@ -0,0 +1,83 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/pkg/errors"
+)
+
+// Scheme is the prompt prefix of urls (e.g. URL:https://example.com).
+const Scheme = "URL"
+
+// Loader loads content at url (see Get), or crawls the site when the url
+// is followed by crawl arguments (see ParseCrawl).
+type Loader struct {
+	Options Options
+}
+
+func (l *Loader) Scheme() string {
+	return Scheme
+}
+
+func (l *Loader) Open(_ context.Context, location string) (content.Source, error) {
+	u, args, _ := strings.Cut(strings.TrimSpace(location), " ")
+	crawl, err := ParseCrawl(args)
+	if err != nil {
+		return nil, err
+	}
+	if crawl == nil && strings.TrimSpace(args) != "" {
+		return nil, errors.Errorf("invalid URL arguments: %s", args)
+	}
+	if !strings.HasPrefix(u, "http") {
+		return nil, errors.Errorf("invalid url %s", Redact(u))
+	}
+	return &source{url: u, crawl: crawl, opt: l.Options}, nil
+}
+
+type source struct {
+	url   string
+	crawl *CrawlOptions
+	opt   Options
+}
+
+func (s *source) Name() string {
+	return Redact(s.url)
+}
+
+func (s *source) Size() int64 {
+	return -1
+}
+
+func (s *source) Info() []string {
+	return nil
+}
+
+func (s *source) Load(ctx context.Context, desc string) (*content.Content, error) {
+	var page *Page
+	var err error
+	if s.crawl != nil {
+		page, err = Crawl(ctx, desc, s.url, *s.crawl, s.opt)
+	} else {
+		page, err = Get(desc, s.url, s.opt)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading URL: %s", Redact(s.url))
+	}
+
+	c := &content.Content{
+		Parts:  page.Parts,
+		Tables: page.Tables,
+	}
+	for _, p := range page.Pages {
+		c.Info = append(c.Info, "  "+Redact(p.URL))
+	}
+	if page.Cached {
+		c.Info = append(c.Info, fmt.Sprintf("Loaded %s content (from cache).", page))
+	} else {
+		c.Info = append(c.Info, fmt.Sprintf("Loaded %s content.", page))
+	}
+	return c, nil
+}
@@ -0,0 +1,162 @@
+package url
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// robots holds the robots.txt rules which apply to this client.
+type robots struct {
+	rules    []rule
+	sitemaps []string
+}
+
+type rule struct {
+	prefix string
+	allow  bool
+}
+
+// allowed reports whether the path can be crawled, the longest matching
+// rule wins and allow wins ties.
+func (r *robots) allowed(path string) bool {
+	best := -1
+	allow := true
+	for _, rl := range r.rules {
+		if !matchRule(rl.prefix, path) {
+			continue
+		}
+		if n := len(rl.prefix); n > best || (n == best && rl.allow) {
+			best, allow = n, rl.allow
+		}
+	}
+	return allow
+}
+
+// matchRule matches path against robots.txt rule with * wildcard and $
+// end anchor.
+func matchRule(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, p := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, p)
+		}
+		j := strings.Index(rest, p)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(p):]
+	}
+	return !anchored || rest == ""
+}
+
+// parseRobots reads the rules of the group for aictl, or of the group for
+// all agents (*) when there is no specific one.
+func parseRobots(r io.Reader) *robots {
+	res := &robots{}
+	var specific, generic []rule
+	var agents []string
+	inRules := false
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// empty disallow allows everything
+				continue
+			}
+			rl := rule{prefix: value, allow: key == "allow"}
+			for _, a := range agents {
+				switch {
+				case a == "*":
+					generic = append(generic, rl)
+				case strings.Contains(a, "aictl"):
+					specific = append(specific, rl)
+				}
+			}
+		case "sitemap":
+			res.sitemaps = append(res.sitemaps, value)
+		}
+	}
+
+	res.rules = generic
+	if len(specific) > 0 {
+		res.rules = specific
+	}
+	return res
+}
+
+// getRobots returns the robots.txt rules of the site, missing file allows
+// everything. The request is subject to the crawl rate limit.
+func (c *crawler) getRobots(ctx context.Context, site string) *robots {
+	if err := c.wait(ctx); err != nil {
+		return &robots{}
+	}
+	r, err := fetch(ctx, c.authorized(site+"/robots.txt"), c.fetch)
+	if err != nil {
+		return &robots{}
+	}
+	return parseRobots(bytes.NewReader(r.body))
+}
+
+type sitemap struct {
+	URLs     []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// getSitemap returns the page urls listed in the sitemap, following the
+// sitemap index one level deep. Each request is subject to the crawl rate
+// limit.
+func (c *crawler) getSitemap(ctx context.Context, loc string, nested bool) []string {
+	if err := c.wait(ctx); err != nil {
+		return nil
+	}
+	r, err := fetch(ctx, c.authorized(loc), c.fetch)
+	if err != nil {
+		return nil
+	}
+
+	var sm sitemap
+	if err := xml.Unmarshal(r.body, &sm); err != nil {
+		return nil
+	}
+
+	urls := sm.URLs
+	if nested {
+		for _, s := range sm.Sitemaps {
+			urls = append(urls, c.getSitemap(ctx, strings.TrimSpace(s), false)...)
+		}
+	}
+	for i, u := range urls {
+		urls[i] = strings.TrimSpace(u)
+	}
+	return urls
+}
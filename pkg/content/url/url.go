@@ -1,80 +1,341 @@
 package url
 
 import (
-	"io"
+	"context"
+	"fmt"
+	"mime"
 	"net/http"
+	neturl "net/url"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/k3a/html2text"
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/mchmarny/aictl/pkg/content/article"
+	"github.com/mchmarny/aictl/pkg/content/pdf"
+	"github.com/mchmarny/aictl/pkg/content/structured"
+	"github.com/mchmarny/aictl/pkg/content/table"
 	"github.com/pkg/errors"
 )
 
 const (
+	// MaxSizeDefault is the default limit of downloaded content size.
+	MaxSizeDefault = 20 * 1024 * 1024
+	// MaxRedirectsDefault is the default limit of followed redirects.
+	MaxRedirectsDefault = 5
+
 	maxIdleConns     = 10
 	timeoutInSeconds = 60
 	clientAgent      = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4896.88 Safari/537.36"
 )
 
+// Type is the type of the loaded content.
+type Type string
+
+const (
+	HTML  Type = "HTML"
+	Text  Type = "text"
+	JSON  Type = "JSON"
+	YAML  Type = "YAML"
+	PDF   Type = "PDF"
+	CSV   Type = "CSV"
+	TSV   Type = "TSV"
+	Image Type = "image"
+)
+
 var (
 	reqTransport = &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		TLSHandshakeTimeout:   timeoutInSeconds * time.Second,
 		MaxIdleConns:          maxIdleConns,
 		IdleConnTimeout:       timeoutInSeconds * time.Second,
 		DisableCompression:    true,
 		DisableKeepAlives:     false,
 		ResponseHeaderTimeout: time.Duration(timeoutInSeconds) * time.Second,
 	}
-)
 
-func getResp(url string) (resp *http.Response, err error) {
-	c := http.Client{
-		Timeout:   time.Duration(timeoutInSeconds) * time.Second,
-		Transport: reqTransport,
+	// types by media type, the suffixes (e.g. application/ld+json) are
+	// matched as well
+	mediaTypes = map[string]Type{
+		"text/html":                 HTML,
+		"application/xhtml+xml":     HTML,
+		"application/json":          JSON,
+		"+json":                     JSON,
+		"application/yaml":          YAML,
+		"application/x-yaml":        YAML,
+		"text/yaml":                 YAML,
+		"text/x-yaml":               YAML,
+		"application/pdf":           PDF,
+		"text/csv":                  CSV,
+		"application/csv":           CSV,
+		"text/tab-separated-values": TSV,
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "error creating HTTP Get request")
+	// types by extension of the url path, used for generic media types
+	extensions = map[string]Type{
+		".html": HTML,
+		".htm":  HTML,
+		".json": JSON,
+		".yaml": YAML,
+		".yml":  YAML,
+		".pdf":  PDF,
+		".csv":  CSV,
+		".tsv":  TSV,
 	}
+)
+
+// Options configures how the content is loaded.
+type Options struct {
+	// FullPage loads the text of the entire HTML page instead of only its
+	// main content.
+	FullPage bool
+
+	// Sample is the number of rows included from CSV data which doesn't
+	// fit into the Budget (in bytes).
+	Sample int
+	Budget int
+
+	// Items is the number of elements included from arrays in JSON and
+	// YAML documents.
+	Items int
+
+	// Cache stores the downloaded content, nil disables caching.
+	Cache *Cache
+	// Offline loads the content only from Cache.
+	Offline bool
 
-	req.Header.Set("User-Agent", clientAgent)
+	// MaxSize is the maximum size of downloaded content in bytes, and
+	// MaxRedirects the maximum number of followed redirects. Defaults are
+	// used when not set.
+	MaxSize      int64
+	MaxRedirects int
 
-	return c.Do(req)
+	// BlockPrivate blocks requests to private, loopback and link-local
+	// addresses (e.g. cloud metadata endpoints), including redirects,
+	// except the hosts, IP addresses, or CIDR ranges in Allow.
+	BlockPrivate bool
+	Allow        []string
+
+	// Credentials authenticate the requests, nil sends no credentials.
+	Credentials *Credentials
+}
+
+// Page is the content loaded from url.
+type Page struct {
+	Type     Type
+	MIMEType string
+
+	// Article is set when only the main content of HTML page was loaded.
+	Article bool
+	// Cached is set when the content was loaded from cache.
+	Cached bool
+
+	Parts  []content.Part
+	Tables []*table.Table
+
+	// Pages loaded by Crawl.
+	Pages []*CrawledPage
 }
 
+// GetContent returns the description followed by the text of the page
+// at url. Returns error when url points to an image, use GetParts instead.
 func GetContent(desc, url string) (string, error) {
+	parts, err := GetParts(desc, url, Options{})
+	if err != nil {
+		return "", err
+	}
+	return content.JoinText(parts)
+}
+
+// GetParts returns the description followed by the content at url
+// (see Get).
+func GetParts(desc, url string, opt Options) ([]content.Part, error) {
+	p, err := Get(desc, url, opt)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parts, nil
+}
+
+// Get returns the description followed by the content at url, loaded
+// according to its type detected from the Content-Type header, the
+// extension of the url path, or the content itself. For HTML pages only
+// the main content is returned as Markdown, preceded by the page metadata,
+// unless the full page is requested or the main content can't be found.
+// JSON and YAML are returned as minified JSON, PDF documents as text, CSV
+// data with its schema and statistics, and images (PNG, JPEG, or WEBP) as
+// blob part. The fragment of the url can select part of JSON document
+// (e.g. #.items[]) or pages of PDF document (e.g. #pages=1-3).
+func Get(desc, url string, opt Options) (*Page, error) {
 	if !strings.HasPrefix(url, "http") {
-		return "", errors.Errorf("invalid url %s", url)
+		return nil, errors.Errorf("invalid url %s", Redact(url))
 	}
 
-	var content strings.Builder
-	content.WriteString(desc)
-	content.WriteString("\n")
+	// fragment is not sent to the server
+	url, selector := structured.SplitPath(url)
+	url, pages := pdf.SplitPath(url)
 
-	// get html content
-	resp, err := getResp(url)
+	r, err := fetch(context.Background(), url, opt)
 	if err != nil {
-		return "", errors.Errorf("error requesting %s", url)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	body := r.body
+
+	p := &Page{Cached: r.cached}
+	p.Type, p.MIMEType = detect(r.url, r.contentType, body)
 
-	if resp.StatusCode == http.StatusNotFound {
-		return "", errors.Errorf("url not found %s", url)
+	var sb strings.Builder
+	sb.WriteString(desc)
+	sb.WriteString("\n")
+
+	switch p.Type {
+	case Image:
+		if len(body) > content.MaxImageSize {
+			return nil, errors.Errorf("image too large %s: %d bytes", Redact(url), len(body))
+		}
+		p.Parts = []content.Part{
+			content.Text(sb.String()),
+			&content.Blob{Name: url, MIMEType: p.MIMEType, Data: body},
+		}
+		return p, nil
+	case JSON, YAML:
+		variant := fmt.Sprintf("%s:%s:%d", p.Type, selector, items(opt))
+		txt, err := r.text(opt.Cache, variant, func() (string, error) {
+			return structured.Read(body, p.Type == YAML, selector, items(opt))
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s content from %s", p.Type, Redact(url))
+		}
+		sb.WriteString(txt)
+		sb.WriteString("\n")
+	case PDF:
+		txt, err := r.text(opt.Cache, "PDF:"+pages, func() (string, error) {
+			return pdf.ReadText(body, pages)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading PDF content from %s", Redact(url))
+		}
+		sb.WriteString(txt)
+	case CSV, TSV:
+		comma := ','
+		if p.Type == TSV {
+			comma = '\t'
+		}
+		t, err := table.Read(baseName(r.url), strings.NewReader(string(body)), comma)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s content from %s", p.Type, Redact(url))
+		}
+		txt, err := t.Content(opt.Sample, opt.Budget)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(txt)
+		p.Tables = []*table.Table{t}
+	case HTML:
+		if !opt.FullPage {
+			// empty text is cached when the main content can't be found
+			txt, err := r.text(opt.Cache, "HTML:main", func() (string, error) {
+				if a, err := article.Extract(body, r.url); err == nil {
+					return a.String(), nil
+				}
+				return "", nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if txt != "" {
+				p.Article = true
+				sb.WriteString(txt)
+				break
+			}
+		}
+		sb.WriteString(html2text.HTML2Text(string(body)))
+	case Text:
+		sb.Write(body)
+		sb.WriteString("\n")
+	default:
+		return nil, errors.Errorf("unsupported content type %s: %s", Redact(url), p.MIMEType)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.Errorf("invalid response %s: %d - %s", url, resp.StatusCode, resp.Status)
+	p.Parts = []content.Part{content.Text(sb.String())}
+	return p, nil
+}
+
+// detect returns the content type and its media type. The declared media
+// type is preferred, generic ones (e.g. text/plain) are refined by the
+// extension of url path. Images are always detected from the content.
+func detect(url, contentType string, body []byte) (Type, string) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	mediaType = strings.ToLower(mediaType)
+
+	if m := content.ImageType(body); m != "" {
+		return Image, m
+	}
+	if content.IsImageType(mediaType) {
+		// declared image with content which isn't supported image
+		return "", mediaType
 	}
 
-	// read html content
-	html := new(strings.Builder)
-	_, err = io.Copy(html, resp.Body)
-	if err != nil {
-		return "", errors.Wrapf(err, "error reading downloaded content from %s", url)
+	if t, ok := mediaTypes[mediaType]; ok {
+		return t, mediaType
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if t, ok := mediaTypes[mediaType[i:]]; ok {
+			return t, mediaType
+		}
 	}
 
-	// convert html to text
-	content.WriteString(html2text.HTML2Text(html.String()))
+	if u, err := neturl.Parse(url); err == nil {
+		if t, ok := extensions[strings.ToLower(path.Ext(u.Path))]; ok {
+			return t, mediaType
+		}
+	}
 
-	return content.String(), nil
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+
+	switch {
+	case mediaType == "text/html":
+		return HTML, mediaType
+	case mediaType == "application/pdf":
+		return PDF, mediaType
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/xml", mediaType == "application/javascript",
+		mediaType == "application/x-sh", mediaType == "application/toml":
+		return Text, mediaType
+	}
+	return "", mediaType
+}
+
+// baseName returns the last element of the url path.
+func baseName(u string) string {
+	if pu, err := neturl.Parse(u); err == nil {
+		return path.Base(pu.Path)
+	}
+	return path.Base(u)
+}
+
+func items(opt Options) int {
+	if opt.Items <= 0 {
+		return structured.ItemsDefault
+	}
+	return opt.Items
+}
+
+// String returns the description of the loaded content.
+func (p *Page) String() string {
+	switch {
+	case len(p.Pages) > 0:
+		return fmt.Sprintf("%d HTML pages", len(p.Pages))
+	case p.Article:
+		return "HTML (main content)"
+	case p.Type == HTML:
+		return "HTML (full page)"
+	case p.Type == Image:
+		return fmt.Sprintf("image (%s)", p.MIMEType)
+	}
+	return string(p.Type)
 }
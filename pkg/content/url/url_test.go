@@ -1,8 +1,13 @@
 package url
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/mchmarny/aictl/pkg/content"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,3 +28,130 @@ func TestGetURLContent(t *testing.T) {
 		assert.Contains(t, content, "Understanding the safety risks of your application")
 	})
 }
+
+const articlePage = `<html><head><title>Release Notes</title></head><body>
+<div class="menu"><a href="/login">Sign in</a></div>
+<main><h2>Changes</h2><ul>
+<li>The loader now extracts the main content of the page, skipping menus, banners and footers.</li>
+<li>Headings, lists, code blocks and tables are converted to Markdown to keep their structure.</li>
+<li>Title, author and published date are included from the page metadata when available.</li>
+</ul></main></body></html>`
+
+func TestGetURLParts(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(png))
+		case "/article":
+			_, _ = w.Write([]byte(articlePage))
+		case "/api/items":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"items": [{"name": "a"}, {"name": "b"}]}`))
+		case "/raw/config.yaml", "/raw/main.go":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if strings.HasSuffix(r.URL.Path, ".yaml") {
+				_, _ = w.Write([]byte("name: web\n"))
+			} else {
+				_, _ = w.Write([]byte("package main\n"))
+			}
+		case "/data/prices.csv":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte("month,price\n2023-01,1.5\n2023-02,1.7\n"))
+		case "/download":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte{0, 1, 2})
+		case "/broken":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("<html></html>"))
+		default:
+			_, _ = w.Write([]byte("<html><body><p>Hello</p></body></html>"))
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("Page", func(t *testing.T) {
+		parts, err := GetParts("desc", srv.URL+"/page", Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []content.Part{content.Text("desc\nHello\r\n\r\n")}, parts)
+	})
+
+	t.Run("Article", func(t *testing.T) {
+		parts, err := GetParts("desc", srv.URL+"/article", Options{})
+		assert.NoError(t, err)
+		txt, err := content.JoinText(parts)
+		assert.NoError(t, err)
+		assert.Contains(t, txt, "desc\nTitle: Release Notes\n\n## Changes\n\n- ")
+		assert.NotContains(t, txt, "/login")
+
+		parts, err = GetParts("desc", srv.URL+"/article", Options{FullPage: true})
+		assert.NoError(t, err)
+		txt, err = content.JoinText(parts)
+		assert.NoError(t, err)
+		assert.Contains(t, txt, "/login")
+	})
+
+	t.Run("Image", func(t *testing.T) {
+		parts, err := GetParts("desc", srv.URL+"/image", Options{})
+		assert.NoError(t, err)
+		assert.Len(t, parts, 2)
+		assert.Equal(t, &content.Blob{Name: srv.URL + "/image", MIMEType: "image/png", Data: []byte(png)}, parts[1])
+
+		_, err = GetContent("desc", srv.URL+"/image")
+		assert.Error(t, err)
+	})
+
+	t.Run("Types", func(t *testing.T) {
+		tests := []struct {
+			path string
+			typ  Type
+			want string
+		}{
+			{"/api/items#.items[].name", JSON, "desc\n[\"a\",\"b\"]\n"},
+			{"/raw/config.yaml", YAML, "desc\n{\"name\":\"web\"}\n"},
+			{"/data/prices.csv", CSV, "desc\nTable prices.csv (2 rows, 2 columns):\n"},
+			{"/raw/main.go", Text, "desc\npackage main\n\n"},
+		}
+		for _, tt := range tests {
+			p, err := Get("desc", srv.URL+tt.path, Options{})
+			assert.NoError(t, err, tt.path)
+			assert.Equal(t, tt.typ, p.Type, tt.path)
+			txt, err := content.JoinText(p.Parts)
+			assert.NoError(t, err)
+			assert.Contains(t, txt, tt.want, tt.path)
+		}
+
+		p, err := Get("desc", srv.URL+"/data/prices.csv", Options{})
+		assert.NoError(t, err)
+		assert.Len(t, p.Tables, 1)
+		assert.Equal(t, "CSV", p.String())
+
+		_, err = Get("desc", srv.URL+"/download", Options{})
+		assert.ErrorContains(t, err, "unsupported content type")
+	})
+
+	t.Run("Loader", func(t *testing.T) {
+		ctx := context.Background()
+		l := &Loader{}
+		src, err := l.Open(ctx, srv.URL+"/data/prices.csv")
+		assert.NoError(t, err)
+		assert.Equal(t, srv.URL+"/data/prices.csv", src.Name())
+		assert.Equal(t, int64(-1), src.Size())
+
+		c, err := src.Load(ctx, "desc")
+		assert.NoError(t, err)
+		assert.Len(t, c.Tables, 1)
+		assert.Equal(t, []string{"Loaded CSV content."}, c.Info)
+
+		_, err = l.Open(ctx, srv.URL+" depth=2")
+		assert.ErrorContains(t, err, "invalid URL arguments")
+		_, err = l.Open(ctx, "example.com")
+		assert.ErrorContains(t, err, "invalid url")
+	})
+
+	t.Run("Invalid image", func(t *testing.T) {
+		_, err := GetParts("desc", srv.URL+"/broken", Options{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,104 @@
+package format
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the shape of the response expected from the model.
+type Format string
+
+const (
+	Text     Format = "text"
+	JSON     Format = "json"
+	Markdown Format = "markdown"
+
+	fence = "```"
+)
+
+var formats = []Format{Text, JSON, Markdown}
+
+// Parse returns the format for the provided name.
+func Parse(name string) (Format, error) {
+	for _, f := range formats {
+		if strings.EqualFold(string(f), strings.TrimSpace(name)) {
+			return f, nil
+		}
+	}
+	return "", errors.Errorf("invalid format '%s', expected one of: %v", name, formats)
+}
+
+// Instruction returns the text appended to each prompt to ask the model
+// to respond in this format.
+func (f Format) Instruction() string {
+	switch f {
+	case JSON:
+		return "Respond only with valid JSON. Do not include any explanation or Markdown formatting."
+	case Markdown:
+		return "Respond using GitHub flavored Markdown."
+	default:
+		return "Respond in plain text without any Markdown formatting."
+	}
+}
+
+// Validate checks that the response matches the format and returns it
+// in its normalized form (e.g. JSON without the code fence).
+func (f Format) Validate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("response is empty")
+	}
+
+	switch f {
+	case JSON:
+		v := ExtractJSON(s)
+		if !json.Valid([]byte(v)) {
+			return "", errors.New("response is not valid JSON")
+		}
+		return v, nil
+	case Markdown:
+		if countFences(s)%2 != 0 {
+			return "", errors.New("response has an unterminated code block")
+		}
+		return s, nil
+	default:
+		return s, nil
+	}
+}
+
+// ExtractJSON returns the JSON document from the response, removing any
+// surrounding code fence or text the model may have added.
+func ExtractJSON(s string) string {
+	s = strings.TrimSpace(s)
+
+	if i := strings.Index(s, fence); i >= 0 {
+		body := s[i+len(fence):]
+		if nl := strings.Index(body, "\n"); nl >= 0 {
+			body = body[nl+1:]
+		}
+		if j := strings.Index(body, fence); j >= 0 {
+			body = body[:j]
+		}
+		return strings.TrimSpace(body)
+	}
+
+	start := strings.IndexAny(s, "{[")
+	end := strings.LastIndexAny(s, "}]")
+	if start >= 0 && end > start {
+		return s[start : end+1]
+	}
+
+	return s
+}
+
+func countFences(s string) int {
+	n := 0
+	for _, l := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), fence) {
+			n++
+		}
+	}
+	return n
+}
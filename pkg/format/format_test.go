@@ -0,0 +1,47 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	f, err := Parse("JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, JSON, f)
+
+	_, err = Parse("xml")
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("JSON in fence", func(t *testing.T) {
+		v, err := JSON.Validate("Here you go:\n```json\n{\"a\": 1}\n```\n")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a": 1}`, v)
+	})
+
+	t.Run("JSON with text", func(t *testing.T) {
+		v, err := JSON.Validate("Result: [1, 2] done")
+		assert.NoError(t, err)
+		assert.Equal(t, `[1, 2]`, v)
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		_, err := JSON.Validate("{a: 1}")
+		assert.Error(t, err)
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		_, err := Markdown.Validate("# Title\n```go\nfmt.Println()\n```")
+		assert.NoError(t, err)
+		_, err = Markdown.Validate("# Title\n```go\nfmt.Println()")
+		assert.Error(t, err)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		_, err := Text.Validate(" \n")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,306 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Schema is a JSON Schema used to validate model responses. It supports
+// the commonly used subset of the specification: type, enum, const,
+// properties, required, additionalProperties, items, length and range
+// constraints, pattern, allOf/anyOf/oneOf/not, and local $ref.
+type Schema struct {
+	raw  []byte
+	root map[string]any
+}
+
+// ValidationError lists all the reasons a document didn't match the schema.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("response does not match schema: %s", strings.Join(e.Errors, "; "))
+}
+
+// LoadSchema reads JSON Schema from the file at path.
+func LoadSchema(path string) (*Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading schema file: %s", path)
+	}
+	return NewSchema(b)
+}
+
+// NewSchema parses JSON Schema document.
+func NewSchema(b []byte) (*Schema, error) {
+	var root map[string]any
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, errors.Wrap(err, "error parsing schema")
+	}
+	return &Schema{raw: b, root: root}, nil
+}
+
+// Instruction returns the text appended to each prompt to ask the model
+// to respond with JSON matching this schema.
+func (s *Schema) Instruction() string {
+	return fmt.Sprintf("%s The JSON must conform to the following JSON Schema:\n%s",
+		JSON.Instruction(), strings.TrimSpace(string(s.raw)))
+}
+
+// Validate checks JSON document against the schema. When the document
+// doesn't match, the returned error is *ValidationError.
+func (s *Schema) Validate(doc []byte) error {
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return &ValidationError{Errors: []string{fmt.Sprintf("invalid JSON: %s", err.Error())}}
+	}
+
+	var errs []string
+	s.validate("$", s.root, v, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, sch map[string]any, v any, errs *[]string) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if ref, ok := sch["$ref"].(string); ok {
+		r, err := s.resolve(ref)
+		if err != nil {
+			fail(err.Error())
+			return
+		}
+		sch = r
+	}
+
+	if t, ok := sch["type"]; ok && !matchesType(t, v) {
+		fail("expected %v, got %s", t, typeOf(v))
+		return
+	}
+
+	if enum, ok := sch["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value must be one of %v", enum)
+		}
+	}
+
+	if c, ok := sch["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("value must be %v", c)
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		s.validateObject(path, sch, val, errs)
+	case []any:
+		if lo, ok := number(sch["minItems"]); ok && float64(len(val)) < lo {
+			fail("expected at least %v items, got %d", lo, len(val))
+		}
+		if hi, ok := number(sch["maxItems"]); ok && float64(len(val)) > hi {
+			fail("expected at most %v items, got %d", hi, len(val))
+		}
+		if items, ok := sch["items"].(map[string]any); ok {
+			for i, item := range val {
+				s.validate(fmt.Sprintf("%s[%d]", path, i), items, item, errs)
+			}
+		}
+	case string:
+		n := float64(utf8.RuneCountInString(val))
+		if lo, ok := number(sch["minLength"]); ok && n < lo {
+			fail("expected at least %v characters", lo)
+		}
+		if hi, ok := number(sch["maxLength"]); ok && n > hi {
+			fail("expected at most %v characters", hi)
+		}
+		if p, ok := sch["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				fail("invalid pattern in schema: %s", p)
+			} else if !re.MatchString(val) {
+				fail("value does not match pattern %s", p)
+			}
+		}
+	case float64:
+		if lo, ok := number(sch["minimum"]); ok && val < lo {
+			fail("value must be >= %v", lo)
+		}
+		if hi, ok := number(sch["maximum"]); ok && val > hi {
+			fail("value must be <= %v", hi)
+		}
+		if lo, ok := number(sch["exclusiveMinimum"]); ok && val <= lo {
+			fail("value must be > %v", lo)
+		}
+		if hi, ok := number(sch["exclusiveMaximum"]); ok && val >= hi {
+			fail("value must be < %v", hi)
+		}
+	}
+
+	if all, ok := sch["allOf"].([]any); ok {
+		for _, sub := range all {
+			if m, ok := sub.(map[string]any); ok {
+				s.validate(path, m, v, errs)
+			}
+		}
+	}
+
+	if anyOf, ok := sch["anyOf"].([]any); ok && s.countMatches(path, anyOf, v) == 0 {
+		fail("value does not match any of the allowed schemas")
+	}
+
+	if oneOf, ok := sch["oneOf"].([]any); ok {
+		if n := s.countMatches(path, oneOf, v); n != 1 {
+			fail("value must match exactly one schema, matched %d", n)
+		}
+	}
+
+	if not, ok := sch["not"].(map[string]any); ok && s.countMatches(path, []any{not}, v) > 0 {
+		fail("value must not match schema")
+	}
+}
+
+func (s *Schema) validateObject(path string, sch map[string]any, val map[string]any, errs *[]string) {
+	props, _ := sch["properties"].(map[string]any)
+
+	if req, ok := sch["required"].([]any); ok {
+		for _, r := range req {
+			if name, ok := r.(string); ok {
+				if _, found := val[name]; !found {
+					*errs = append(*errs, fmt.Sprintf("%s: missing required property '%s'", path, name))
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(val))
+	for k := range val {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := fmt.Sprintf("%s.%s", path, k)
+		if ps, ok := props[k].(map[string]any); ok {
+			s.validate(p, ps, val[k], errs)
+			continue
+		}
+		switch ap := sch["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				*errs = append(*errs, fmt.Sprintf("%s: property is not allowed", p))
+			}
+		case map[string]any:
+			s.validate(p, ap, val[k], errs)
+		}
+	}
+}
+
+func (s *Schema) countMatches(path string, schemas []any, v any) int {
+	n := 0
+	for _, sub := range schemas {
+		m, ok := sub.(map[string]any)
+		if !ok {
+			continue
+		}
+		var errs []string
+		s.validate(path, m, v, &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Schema) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, errors.Errorf("unsupported schema reference: %s", ref)
+	}
+
+	var cur any = s.root
+	for _, p := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if p == "" {
+			continue
+		}
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, errors.Errorf("invalid schema reference: %s", ref)
+		}
+		cur = m[strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")]
+	}
+
+	m, ok := cur.(map[string]any)
+	if !ok {
+		return nil, errors.Errorf("invalid schema reference: %s", ref)
+	}
+	return m, nil
+}
+
+func matchesType(t any, v any) bool {
+	switch tt := t.(type) {
+	case string:
+		return isType(tt, v)
+	case []any:
+		for _, x := range tt {
+			if s, ok := x.(string); ok && isType(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isType(t string, v any) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return typeOf(v) == t
+	}
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func number(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
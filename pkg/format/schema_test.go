@@ -0,0 +1,66 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["name", "tags"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2},
+		"age": {"type": "integer", "minimum": 0},
+		"kind": {"enum": ["a", "b"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"$ref": "#/$defs/tag"}}
+	},
+	"$defs": {
+		"tag": {"type": "string", "pattern": "^[a-z]+$"}
+	}
+}`
+
+func TestSchema(t *testing.T) {
+	s, err := NewSchema([]byte(testSchema))
+	assert.NoError(t, err)
+	assert.Contains(t, s.Instruction(), `"required"`)
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, s.Validate([]byte(`{"name": "ab", "age": 3, "kind": "a", "tags": ["x"]}`)))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		err := s.Validate([]byte(`{"name": "a", "age": 1.5, "kind": "c", "tags": ["X", "y", "z"], "extra": 1}`))
+		assert.Error(t, err)
+		verr, ok := err.(*ValidationError)
+		assert.True(t, ok)
+		assert.Contains(t, verr.Errors, "$.name: expected at least 2 characters")
+		assert.Contains(t, verr.Errors, "$.age: expected integer, got number")
+		assert.Contains(t, verr.Errors, "$.extra: property is not allowed")
+		assert.Contains(t, verr.Errors, "$.tags: expected at most 2 items, got 3")
+		assert.Contains(t, verr.Errors, "$.tags[0]: value does not match pattern ^[a-z]+$")
+		assert.Len(t, verr.Errors, 6)
+	})
+
+	t.Run("Missing required", func(t *testing.T) {
+		err := s.Validate([]byte(`{"name": "ab"}`))
+		assert.ErrorContains(t, err, "missing required property 'tags'")
+	})
+
+	t.Run("Not JSON", func(t *testing.T) {
+		assert.Error(t, s.Validate([]byte(`nope`)))
+	})
+}
+
+func TestLoadSchema(t *testing.T) {
+	_, err := LoadSchema("schema-not-exists.json")
+	assert.Error(t, err)
+
+	p := filepath.Join(t.TempDir(), "s.json")
+	assert.NoError(t, os.WriteFile(p, []byte(testSchema), 0o600))
+	_, err = LoadSchema(p)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,131 @@
+package index
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ChunkSizeDefault is the default maximum size of chunk in bytes.
+	ChunkSizeDefault = 1500
+	// OverlapDefault is the default size of the text repeated from the end
+	// of previous chunk, so the context isn't lost at chunk boundaries.
+	OverlapDefault = 200
+)
+
+// Split splits text into chunks of up to size bytes (plus overlap). The
+// chunks are split at paragraphs when possible, then at lines and words.
+func Split(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = ChunkSizeDefault
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	var chunks []string
+	var sb strings.Builder
+	flush := func() {
+		if c := strings.TrimSpace(sb.String()); c != "" {
+			chunks = append(chunks, c)
+		}
+		sb.Reset()
+	}
+
+	for _, p := range pieces(text, size) {
+		if sb.Len() > 0 && sb.Len()+len(p)+2 > size {
+			prev := sb.String()
+			flush()
+			if overlap > 0 {
+				sb.WriteString(tail(prev, overlap))
+				sb.WriteString("\n\n")
+			}
+		}
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n\n") {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(p)
+	}
+	flush()
+	return chunks
+}
+
+// pieces returns the paragraphs of text, splitting the ones longer than
+// size at lines, and then at words.
+func pieces(text string, size int) []string {
+	var list []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "":
+		case len(p) <= size:
+			list = append(list, p)
+		default:
+			list = append(list, pack(strings.Split(p, "\n"), "\n", size)...)
+		}
+	}
+	return list
+}
+
+// pack joins the parts using sep into strings of up to size bytes, parts
+// longer than size are split at words.
+func pack(parts []string, sep string, size int) []string {
+	var list []string
+	var cur string
+	for _, p := range parts {
+		if len(p) > size {
+			if cur != "" {
+				list = append(list, cur)
+				cur = ""
+			}
+			if sep == " " {
+				// single word longer than size
+				list = append(list, cut(p, size)...)
+				continue
+			}
+			list = append(list, pack(strings.Fields(p), " ", size)...)
+			continue
+		}
+		if cur != "" && len(cur)+len(sep)+len(p) > size {
+			list = append(list, cur)
+			cur = ""
+		}
+		if cur != "" {
+			cur += sep
+		}
+		cur += p
+	}
+	if cur != "" {
+		list = append(list, cur)
+	}
+	return list
+}
+
+// cut splits s into strings of up to size bytes at rune boundaries.
+func cut(s string, size int) []string {
+	var list []string
+	for len(s) > size {
+		i := size
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		if i == 0 {
+			i = size
+		}
+		list = append(list, s[:i])
+		s = s[i:]
+	}
+	return append(list, s)
+}
+
+// tail returns the end of s up to n bytes, starting at a word.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	t := s[len(s)-n:]
+	if i := strings.IndexAny(t, " \n"); i >= 0 {
+		t = t[i+1:]
+	}
+	return strings.TrimSpace(t)
+}
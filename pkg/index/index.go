@@ -0,0 +1,213 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TopDefault is the default number of chunks returned by Search.
+	TopDefault = 5
+
+	indexFile = "aictl/index.json"
+)
+
+// Embedder computes embeddings of documents and queries.
+type Embedder interface {
+	// Model is the name of the embedding model, embeddings of different
+	// models can't be compared.
+	Model() string
+	// EmbedDocuments returns the embeddings of the texts, in their order.
+	EmbedDocuments(ctx context.Context, title string, texts []string) ([][]float32, error)
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// Chunk is a part of the indexed content.
+type Chunk struct {
+	Source string    `json:"source"`
+	Title  string    `json:"title,omitempty"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// Result is chunk found by Search.
+type Result struct {
+	*Chunk
+	Score float64
+}
+
+// Source is the indexed content.
+type Source struct {
+	Source string
+	Title  string
+	Chunks int
+}
+
+// Index stores the embeddings of content chunks in a local file.
+type Index struct {
+	Model  string   `json:"model"`
+	Chunks []*Chunk `json:"chunks"`
+
+	path string
+}
+
+// DefaultPath returns the path of the index in the user cache directory
+// (e.g. ~/.cache/aictl/index.json on Linux).
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "error finding user cache directory")
+	}
+	return filepath.Join(dir, indexFile), nil
+}
+
+// Open loads the index from path, empty index is returned when the file
+// doesn't exist yet.
+func Open(path string) (*Index, error) {
+	ix := &Index{path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading index: %s", path)
+	}
+	if err := json.Unmarshal(b, ix); err != nil {
+		return nil, errors.Wrapf(err, "error parsing index: %s", path)
+	}
+	return ix, nil
+}
+
+// Save writes the index to its file.
+func (ix *Index) Save() error {
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o700); err != nil {
+		return errors.Wrapf(err, "error creating index directory: %s", ix.path)
+	}
+	b, err := json.Marshal(ix)
+	if err != nil {
+		return errors.Wrap(err, "error encoding index")
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return errors.Wrapf(err, "error writing index: %s", ix.path)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return errors.Wrapf(err, "error writing index: %s", ix.path)
+	}
+	return nil
+}
+
+func (ix *Index) check(e Embedder) error {
+	if ix.Model != "" && ix.Model != e.Model() && len(ix.Chunks) > 0 {
+		return errors.Errorf("index was created using %s model, can't use %s", ix.Model, e.Model())
+	}
+	ix.Model = e.Model()
+	return nil
+}
+
+// Add splits the text into chunks and adds their embeddings to the index,
+// replacing the chunks previously added from the same source. Returns the
+// number of added chunks.
+func (ix *Index) Add(ctx context.Context, e Embedder, source, title, text string) (int, error) {
+	if err := ix.check(e); err != nil {
+		return 0, err
+	}
+
+	texts := Split(text, ChunkSizeDefault, OverlapDefault)
+	vectors, err := e.EmbedDocuments(ctx, title, texts)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error embedding content of %s", source)
+	}
+	if len(vectors) != len(texts) {
+		return 0, errors.Errorf("error embedding content of %s: %d embeddings for %d chunks", source, len(vectors), len(texts))
+	}
+
+	chunks := make([]*Chunk, 0, len(texts))
+	for i, t := range texts {
+		chunks = append(chunks, &Chunk{Source: source, Title: title, Text: t, Vector: vectors[i]})
+	}
+
+	ix.Remove(source)
+	ix.Chunks = append(ix.Chunks, chunks...)
+	return len(chunks), nil
+}
+
+// Remove removes the chunks of source from the index and returns their
+// number.
+func (ix *Index) Remove(source string) int {
+	kept := ix.Chunks[:0]
+	for _, c := range ix.Chunks {
+		if c.Source != source {
+			kept = append(kept, c)
+		}
+	}
+	n := len(ix.Chunks) - len(kept)
+	ix.Chunks = kept
+	return n
+}
+
+// Sources returns the indexed content in the order it was added.
+func (ix *Index) Sources() []*Source {
+	var list []*Source
+	seen := map[string]*Source{}
+	for _, c := range ix.Chunks {
+		s, ok := seen[c.Source]
+		if !ok {
+			s = &Source{Source: c.Source, Title: c.Title}
+			seen[c.Source] = s
+			list = append(list, s)
+		}
+		s.Chunks++
+	}
+	return list
+}
+
+// Search returns up to top chunks most similar to the query.
+func (ix *Index) Search(ctx context.Context, e Embedder, query string, top int) ([]*Result, error) {
+	if len(ix.Chunks) == 0 {
+		return nil, errors.New("index is empty")
+	}
+	if err := ix.check(e); err != nil {
+		return nil, err
+	}
+	if top <= 0 {
+		top = TopDefault
+	}
+
+	q, err := e.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "error embedding query")
+	}
+
+	list := make([]*Result, 0, len(ix.Chunks))
+	for _, c := range ix.Chunks {
+		list = append(list, &Result{Chunk: c, Score: cosine(q, c.Vector)})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	if len(list) > top {
+		list = list[:top]
+	}
+	return list, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
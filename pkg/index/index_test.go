@@ -0,0 +1,95 @@
+package index
+
+import (
+	"context"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// words embeds text as counts of hashed words.
+type words struct {
+	model string
+}
+
+func (w *words) Model() string {
+	return w.model
+}
+
+func (w *words) EmbedDocuments(_ context.Context, _ string, texts []string) ([][]float32, error) {
+	var list [][]float32
+	for _, t := range texts {
+		list = append(list, w.embed(t))
+	}
+	return list, nil
+}
+
+func (w *words) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return w.embed(text), nil
+}
+
+func (w *words) embed(text string) []float32 {
+	v := make([]float32, 64)
+	for _, f := range strings.Fields(strings.ToLower(text)) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(strings.Trim(f, ".,?")))
+		v[h.Sum32()%64]++
+	}
+	return v
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	e := &words{model: "words"}
+	path := filepath.Join(t.TempDir(), "index.json")
+
+	ix, err := Open(path)
+	assert.NoError(t, err)
+	_, err = ix.Search(ctx, e, "query", 1)
+	assert.ErrorContains(t, err, "index is empty")
+
+	n, err := ix.Add(ctx, e, "gas.md", "", "Gas prices rose in the summer.\n\nThe average price of gas was 3.5 dollars.")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = ix.Add(ctx, e, "cities.md", "", "Tokyo is the largest city in the world.")
+	assert.NoError(t, err)
+	_, err = ix.Add(ctx, e, "gas.md", "", "The average price of gas was 3.5 dollars.")
+	assert.NoError(t, err)
+	assert.Len(t, ix.Chunks, 2)
+	assert.NoError(t, ix.Save())
+
+	ix, err = Open(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "words", ix.Model)
+	assert.Equal(t, []*Source{{Source: "cities.md", Chunks: 1}, {Source: "gas.md", Chunks: 1}}, ix.Sources())
+
+	list, err := ix.Search(ctx, e, "What was the average price of gas?", 1)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "gas.md", list[0].Source)
+
+	_, err = ix.Search(ctx, &words{model: "other"}, "gas", 1)
+	assert.ErrorContains(t, err, "created using words model")
+
+	assert.Equal(t, 1, ix.Remove("gas.md"))
+	assert.Len(t, ix.Chunks, 1)
+}
+
+func TestSplit(t *testing.T) {
+	assert.Empty(t, Split(" \n\n ", 100, 10))
+	assert.Equal(t, []string{"a b\n\nc d"}, Split("a b\n\nc d", 100, 10))
+
+	text := strings.Repeat("word ", 100) + "\n\n" + strings.Repeat("next ", 50)
+	chunks := Split(text, 200, 20)
+	assert.Greater(t, len(chunks), 3)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, len(c), 200+20+2)
+	}
+	// overlap with previous chunk
+	assert.True(t, strings.HasPrefix(chunks[1], "word"))
+
+	assert.Equal(t, []string{"abc", "def", "g"}, Split("abcdefg", 3, 0))
+}
@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in Markdown text.
+type CodeBlock struct {
+	Lang string
+	Code string
+}
+
+// CodeBlocks returns all fenced code blocks in s in the order they appear.
+// Unterminated block at the end of s is included.
+func CodeBlocks(s string) []*CodeBlock {
+	var list []*CodeBlock
+	var cur *CodeBlock
+	var fence string
+	var code strings.Builder
+
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimRight(l, "\r")
+		m := fenceExp.FindStringSubmatch(l)
+		switch {
+		case cur == nil && m != nil:
+			cur = &CodeBlock{Lang: strings.ToLower(m[2])}
+			fence = m[1][:3]
+			code.Reset()
+		case cur != nil && m != nil && strings.HasPrefix(m[1], fence) && m[2] == "":
+			cur.Code = code.String()
+			list = append(list, cur)
+			cur = nil
+		case cur != nil:
+			code.WriteString(l)
+			code.WriteString("\n")
+		}
+	}
+
+	if cur != nil {
+		cur.Code = code.String()
+		list = append(list, cur)
+	}
+
+	return list
+}
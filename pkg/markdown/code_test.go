@@ -0,0 +1,18 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	s := "Intro\n```go\nfunc a() {}\n```\ntext\n~~~\nplain\n~~~\n```sh\necho hi"
+	list := CodeBlocks(s)
+	assert.Len(t, list, 3)
+	assert.Equal(t, &CodeBlock{Lang: "go", Code: "func a() {}\n"}, list[0])
+	assert.Equal(t, &CodeBlock{Lang: "", Code: "plain\n"}, list[1])
+	assert.Equal(t, &CodeBlock{Lang: "sh", Code: "echo hi\n"}, list[2])
+
+	assert.Empty(t, CodeBlocks("no code"))
+}
@@ -0,0 +1,150 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+var (
+	keywordStyle = color.New(color.FgMagenta)
+	stringStyle  = color.New(color.FgYellow)
+	numberStyle  = color.New(color.FgCyan)
+	commentStyle = color.New(color.Faint)
+)
+
+type language struct {
+	keywords map[string]bool
+	comments []string
+	quotes   string
+}
+
+var languages = map[string]*language{}
+
+func init() {
+	register := func(l *language, names ...string) {
+		for _, n := range names {
+			languages[n] = l
+		}
+	}
+
+	register(lang(`break case chan const continue default defer else fallthrough
+		for func go goto if import interface map package range return select struct
+		switch type var nil true false`, "\"'`", "//"), "go", "golang")
+	register(lang(`and as assert async await break class continue def del elif else
+		except finally for from global if import in is lambda nonlocal not or pass
+		raise return try while with yield None True False`, "\"'", "#"), "python", "py")
+	register(lang(`break case catch class const continue default delete do else
+		export extends finally for from function if import in instanceof let new
+		return super switch this throw try typeof var void while yield async await
+		interface type enum null undefined true false`, "\"'`", "//"),
+		"javascript", "js", "typescript", "ts", "jsx", "tsx")
+	register(lang(`if then else elif fi for while until do done case esac in function
+		return local export echo exit set unset source`, "\"'", "#"), "bash", "sh", "shell", "zsh", "console")
+	register(lang(`select from where and or not insert into values update set delete
+		create table drop alter index join left right inner outer on group by order
+		having limit as distinct null is in between like union all case when then
+		else end count sum avg min max`, "'\"", "--"), "sql")
+	register(lang(`true false null`, "\"", ""), "json")
+	register(lang(`true false null yes no`, "\"'", "#"), "yaml", "yml", "toml")
+	register(lang(`abstract boolean break byte case catch char class const continue
+		default do double else enum extends final finally float for if implements
+		import instanceof int interface long new package private protected public
+		return short static super switch this throw throws try void while null true
+		false`, "\"'", "//"), "java", "kotlin", "scala", "csharp", "cs")
+	register(lang(`auto break case char const continue default do double else enum
+		extern float for goto if int long register return short signed sizeof static
+		struct switch typedef union unsigned void volatile while class namespace
+		template public private protected virtual new delete nullptr true false
+		include define`, "\"'", "//"), "c", "cpp", "c++", "h", "hpp")
+	register(lang(`as break const continue crate else enum extern false fn for if
+		impl in let loop match mod move mut pub ref return self Self static struct
+		super trait true type unsafe use where while async await dyn`, "\"", "//"), "rust", "rs")
+}
+
+func lang(keywords, quotes string, comments ...string) *language {
+	l := &language{
+		keywords: map[string]bool{},
+		quotes:   quotes,
+	}
+	for _, k := range strings.Fields(keywords) {
+		l.keywords[k] = true
+	}
+	for _, c := range comments {
+		if c != "" {
+			l.comments = append(l.comments, c)
+		}
+	}
+	return l
+}
+
+// Highlight returns single line of code in the named language with syntax
+// highlighting. Lines in unknown languages are returned unchanged.
+func Highlight(name, line string) string {
+	l, ok := languages[strings.ToLower(name)]
+	if !ok {
+		return line
+	}
+
+	var sb strings.Builder
+	rs := []rune(line)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		rest := string(rs[i:])
+
+		// comments run to the end of line
+		for _, c := range l.comments {
+			if strings.HasPrefix(rest, c) {
+				sb.WriteString(commentStyle.Sprint(rest))
+				return sb.String()
+			}
+		}
+
+		switch {
+		case strings.ContainsRune(l.quotes, r):
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				if rs[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(rs) {
+				j = len(rs) - 1
+			}
+			sb.WriteString(stringStyle.Sprint(string(rs[i : j+1])))
+			i = j + 1
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || unicode.IsLetter(rs[j]) || rs[j] == '.' || rs[j] == '_') {
+				j++
+			}
+			sb.WriteString(numberStyle.Sprint(string(rs[i:j])))
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			word := string(rs[i:j])
+			if l.keywords[word] || l.keywords[strings.ToLower(word)] && isCaseInsensitive(l) {
+				sb.WriteString(keywordStyle.Sprint(word))
+			} else {
+				sb.WriteString(word)
+			}
+			i = j
+		default:
+			sb.WriteRune(r)
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+// isCaseInsensitive reports whether the language keywords are case
+// insensitive (i.e. SQL).
+func isCaseInsensitive(l *language) bool {
+	return l == languages["sql"]
+}
@@ -0,0 +1,378 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+const (
+	defaultWidth = 80
+	codeIndent   = "  "
+)
+
+var (
+	titleStyle   = color.New(color.FgCyan, color.Bold, color.Underline)
+	headingStyle = color.New(color.FgCyan, color.Bold)
+	boldStyle    = color.New(color.Bold)
+	italicStyle  = color.New(color.Italic)
+	codeStyle    = color.New(color.FgYellow)
+	linkStyle    = color.New(color.FgBlue, color.Underline)
+	dimStyle     = color.New(color.Faint)
+
+	headingExp = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	listExp    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	ruleExp    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	fenceExp   = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([^\\s`]*)")
+	sepCellExp = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+	ansiExp    = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// Renderer is an io.Writer which renders streamed Markdown for display in
+// terminal. Input is processed line by line as it arrives, tables are
+// buffered until the last row is received. Call Flush after the last write
+// to render any buffered content.
+type Renderer struct {
+	out   io.Writer
+	width int
+	base  *color.Color
+
+	buf   []byte
+	fence string
+	lang  string
+	table []string
+}
+
+// NewRenderer creates a renderer writing to out, wrapping text at width
+// columns and printing regular text in base style.
+func NewRenderer(out io.Writer, width int, base *color.Color) *Renderer {
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if base == nil {
+		base = color.New(color.Reset)
+	}
+	return &Renderer{
+		out:   out,
+		width: width,
+		base:  base,
+	}
+}
+
+// Write buffers p and renders all the complete lines.
+func (r *Renderer) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	for {
+		i := bytes.IndexByte(r.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(r.buf[:i])
+		r.buf = r.buf[i+1:]
+		if err := r.line(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush renders the remaining partial line and any buffered table.
+func (r *Renderer) Flush() error {
+	if len(r.buf) > 0 {
+		line := string(r.buf)
+		r.buf = nil
+		if err := r.line(line); err != nil {
+			return err
+		}
+	}
+	if err := r.flushTable(); err != nil {
+		return err
+	}
+	r.fence = ""
+	r.lang = ""
+	return nil
+}
+
+func (r *Renderer) line(l string) error {
+	l = strings.TrimRight(l, "\r")
+
+	// code blocks
+	if m := fenceExp.FindStringSubmatch(l); m != nil {
+		if r.fence == "" {
+			if err := r.flushTable(); err != nil {
+				return err
+			}
+			r.fence = m[1][:3]
+			r.lang = strings.ToLower(m[2])
+			return r.print(dimStyle.Sprint(r.rule(r.lang)))
+		}
+		if strings.HasPrefix(m[1], r.fence) && m[2] == "" {
+			r.fence = ""
+			r.lang = ""
+			return r.print(dimStyle.Sprint(r.rule("")))
+		}
+	}
+	if r.fence != "" {
+		return r.print(codeIndent + Highlight(r.lang, l))
+	}
+
+	// tables
+	if strings.HasPrefix(strings.TrimSpace(l), "|") {
+		r.table = append(r.table, l)
+		return nil
+	}
+	if err := r.flushTable(); err != nil {
+		return err
+	}
+
+	trimmed := strings.TrimSpace(l)
+	switch {
+	case trimmed == "":
+		return r.print("")
+	case ruleExp.MatchString(l):
+		return r.print(dimStyle.Sprint(strings.Repeat("─", r.width)))
+	case headingExp.MatchString(l):
+		m := headingExp.FindStringSubmatch(l)
+		txt := strings.TrimRight(m[2], " #")
+		if len(m[1]) == 1 {
+			return r.print(titleStyle.Sprint(txt))
+		}
+		return r.print(headingStyle.Sprint(txt))
+	case strings.HasPrefix(trimmed, ">"):
+		txt := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+		return r.wrap(r.inline(txt), dimStyle.Sprint("│ "), dimStyle.Sprint("│ "))
+	case listExp.MatchString(l):
+		m := listExp.FindStringSubmatch(l)
+		indent := strings.Repeat(" ", len(m[1]))
+		marker := m[2]
+		if strings.ContainsAny(marker, "-*+") {
+			marker = "•"
+		}
+		first := indent + marker + " "
+		next := strings.Repeat(" ", VisibleLen(first))
+		return r.wrap(r.inline(m[3]), first, next)
+	default:
+		return r.wrap(r.inline(trimmed), "", "")
+	}
+}
+
+func (r *Renderer) rule(label string) string {
+	if label == "" {
+		return strings.Repeat("─", r.width)
+	}
+	label = fmt.Sprintf("── %s ", label)
+	n := r.width - VisibleLen(label)
+	if n < 0 {
+		n = 0
+	}
+	return label + strings.Repeat("─", n)
+}
+
+func (r *Renderer) print(s string) error {
+	_, err := fmt.Fprintln(r.out, s)
+	return err
+}
+
+// wrap prints the styled words wrapped at the renderer width with first
+// line prefix and the prefix of all subsequent lines.
+func (r *Renderer) wrap(words []string, first, next string) error {
+	var sb strings.Builder
+	sb.WriteString(first)
+	n := VisibleLen(first)
+	start := n
+
+	for _, w := range words {
+		wl := VisibleLen(w)
+		if n > start && n+1+wl > r.width {
+			sb.WriteString("\n")
+			sb.WriteString(next)
+			n = VisibleLen(next)
+			start = n
+		} else if n > start {
+			sb.WriteString(" ")
+			n++
+		}
+		sb.WriteString(w)
+		n += wl
+	}
+
+	return r.print(sb.String())
+}
+
+// inline renders the inline Markdown (emphasis, code and links) and returns
+// the resulting styled words.
+func (r *Renderer) inline(s string) []string {
+	s = strings.ReplaceAll(s, "\t", " ")
+
+	var words []string
+	var word strings.Builder
+	emit := func(txt string, st *color.Color) {
+		for i, part := range strings.Split(txt, " ") {
+			if i > 0 && word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			if part != "" {
+				word.WriteString(st.Sprint(part))
+			}
+		}
+	}
+
+	for i := 0; i < len(s); {
+		if txt, st, n := span(s[i:]); n > 0 {
+			emit(txt, st)
+			if st == linkStyle {
+				emit(" ("+linkTarget(s[i:i+n])+")", dimStyle)
+			}
+			i += n
+			continue
+		}
+		j := len(s)
+		if k := strings.IndexAny(s[i+1:], "`*_["); k >= 0 {
+			j = i + 1 + k
+		}
+		emit(s[i:j], r.base)
+		i = j
+	}
+
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// span parses styled span at the beginning of s and returns its text,
+// style and the number of consumed bytes, or 0 when s doesn't start with
+// a complete span.
+func span(s string) (string, *color.Color, int) {
+	switch {
+	case s[0] == '`':
+		if j := strings.IndexByte(s[1:], '`'); j >= 0 {
+			return s[1 : 1+j], codeStyle, j + 2
+		}
+	case strings.HasPrefix(s, "**") || strings.HasPrefix(s, "__"):
+		if j := strings.Index(s[2:], s[:2]); j > 0 {
+			return s[2 : 2+j], boldStyle, j + 4
+		}
+	case s[0] == '*':
+		if j := strings.IndexByte(s[1:], '*'); j > 0 && s[1] != ' ' {
+			return s[1 : 1+j], italicStyle, j + 2
+		}
+	case s[0] == '[':
+		if j := strings.Index(s, "]("); j > 0 {
+			if k := strings.IndexByte(s[j:], ')'); k > 0 {
+				return s[1:j], linkStyle, j + k + 1
+			}
+		}
+	}
+	return "", nil, 0
+}
+
+func linkTarget(s string) string {
+	i := strings.Index(s, "](")
+	return s[i+2 : len(s)-1]
+}
+
+func (r *Renderer) flushTable() error {
+	if len(r.table) == 0 {
+		return nil
+	}
+	rows := r.table
+	r.table = nil
+
+	var cells [][]string
+	header := -1
+	for i, row := range rows {
+		c := splitRow(row)
+		if i == 1 && isSeparator(c) {
+			header = 0
+			continue
+		}
+		cells = append(cells, c)
+	}
+
+	// column widths
+	var widths []int
+	for _, row := range cells {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := VisibleLen(strings.Join(r.inline(c), " ")); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	border := func(left, mid, right string) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return dimStyle.Sprint(left + strings.Join(parts, mid) + right)
+	}
+
+	if err := r.print(border("┌", "┬", "┐")); err != nil {
+		return err
+	}
+	for i, row := range cells {
+		var sb strings.Builder
+		sb.WriteString(dimStyle.Sprint("│"))
+		for j, w := range widths {
+			var txt string
+			if j < len(row) {
+				words := r.inline(row[j])
+				if i == header {
+					for k := range words {
+						words[k] = boldStyle.Sprint(ansiExp.ReplaceAllString(words[k], ""))
+					}
+				}
+				txt = strings.Join(words, " ")
+			}
+			sb.WriteString(" ")
+			sb.WriteString(txt)
+			sb.WriteString(strings.Repeat(" ", w-VisibleLen(txt)+1))
+			sb.WriteString(dimStyle.Sprint("│"))
+		}
+		if err := r.print(sb.String()); err != nil {
+			return err
+		}
+		if i == header {
+			if err := r.print(border("├", "┼", "┤")); err != nil {
+				return err
+			}
+		}
+	}
+	return r.print(border("└", "┴", "┘"))
+}
+
+func splitRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+	cells := strings.Split(row, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func isSeparator(cells []string) bool {
+	for _, c := range cells {
+		if !sepCellExp.MatchString(c) {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+// VisibleLen returns the number of characters in s as displayed in terminal,
+// ignoring the color escape sequences.
+func VisibleLen(s string) int {
+	return utf8.RuneCountInString(ansiExp.ReplaceAllString(s, ""))
+}
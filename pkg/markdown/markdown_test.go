@@ -0,0 +1,74 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func render(t *testing.T, width int, chunks ...string) string {
+	t.Helper()
+	var sb strings.Builder
+	r := NewRenderer(&sb, width, nil)
+	for _, c := range chunks {
+		_, err := r.Write([]byte(c))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, r.Flush())
+	return sb.String()
+}
+
+func TestRenderer(t *testing.T) {
+	color.NoColor = true
+
+	t.Run("Heading and emphasis", func(t *testing.T) {
+		out := render(t, 80, "# Ti", "tle\nSome **bold** and `code`.\n")
+		assert.Equal(t, "Title\nSome bold and code.\n", out)
+	})
+
+	t.Run("Wrap", func(t *testing.T) {
+		out := render(t, 10, "one two three four")
+		assert.Equal(t, "one two\nthree four\n", out)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		out := render(t, 12, "- first item here\n2. second\n")
+		assert.Equal(t, "• first item\n  here\n2. second\n", out)
+	})
+
+	t.Run("Link", func(t *testing.T) {
+		out := render(t, 80, "See [docs](https://x.dev).")
+		assert.Equal(t, "See docs (https://x.dev).\n", out)
+	})
+
+	t.Run("Code", func(t *testing.T) {
+		out := render(t, 20, "```go\nfunc main() {}\n```\nend")
+		assert.Equal(t, "── go ──────────────\n  func main() {}\n────────────────────\nend\n", out)
+	})
+
+	t.Run("Table", func(t *testing.T) {
+		out := render(t, 80, "| a | bb |\n|---|---|\n| 1 | 2 |\n\n")
+		assert.Equal(t, strings.Join([]string{
+			"┌───┬────┐",
+			"│ a │ bb │",
+			"├───┼────┤",
+			"│ 1 │ 2  │",
+			"└───┴────┘",
+			"",
+		}, "\n")+"\n", out)
+	})
+}
+
+func TestHighlight(t *testing.T) {
+	color.NoColor = false
+	defer func() { color.NoColor = true }()
+
+	line := `x := "a" // c`
+	assert.Equal(t, "plain", Highlight("unknown", "plain"))
+	out := Highlight("go", line)
+	assert.NotEqual(t, line, out)
+	assert.Equal(t, len(line), VisibleLen(out))
+	assert.Contains(t, Highlight("sql", "SELECT 1"), keywordStyle.Sprint("SELECT"))
+}
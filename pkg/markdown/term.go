@@ -0,0 +1,25 @@
+package markdown
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/mattn/go-isatty"
+)
+
+// IsTerminal reports whether f is an interactive terminal.
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Width returns the width of terminal attached to f. It falls back on the
+// COLUMNS environment variable and then on the default width of 80.
+func Width(f *os.File) int {
+	if w := termWidth(f); w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return defaultWidth
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package markdown
+
+import "os"
+
+func termWidth(_ *os.File) int {
+	return 0
+}
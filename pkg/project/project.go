@@ -0,0 +1,143 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mchmarny/aictl/pkg/content/file"
+	"github.com/mchmarny/aictl/pkg/content/url"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Dir is the directory with project files.
+	Dir = ".aictl"
+
+	instructionsFile = "instructions.md"
+	contextFile      = "context.yaml"
+)
+
+// Project is the project background loaded into each chat started in the
+// project directory or its subdirectories.
+type Project struct {
+	// Root is the directory which includes the project Dir.
+	Root string
+	// Instructions is the path of the instructions file, or empty.
+	Instructions string
+	// Context are the prompts loading the project files (e.g.
+	// FILE:/project/docs/*.md), with paths resolved from Root.
+	Context []string
+	// URLs are the prompts loading the project URLs (e.g.
+	// URL:https://go.dev/doc/effective_go), which are loaded only once
+	// confirmed.
+	URLs []string
+}
+
+// context file, paths are relative to the project root
+type contextSpec struct {
+	Files []string `yaml:"files"`
+	URLs  []string `yaml:"urls"`
+}
+
+// Find returns the project in dir or in the closest parent directory, or
+// nil when there is none.
+func Find(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving directory: %s", dir)
+	}
+	for {
+		info, err := os.Stat(filepath.Join(dir, Dir))
+		if err == nil && info.IsDir() {
+			return Load(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load returns the project in root directory.
+func Load(root string) (*Project, error) {
+	p := &Project{Root: root}
+	dir := filepath.Join(root, Dir)
+
+	instructions := filepath.Join(dir, instructionsFile)
+	if _, err := os.Stat(instructions); err == nil {
+		if outside(root, filepath.Join(Dir, instructionsFile)) {
+			return nil, errors.Errorf("project file outside of project directory %s: %s", root, instructions)
+		}
+		p.Instructions = instructions
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, contextFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading project context: %s", dir)
+	}
+
+	var spec contextSpec
+	if err := yaml.Unmarshal(b, &spec); err != nil {
+		return nil, errors.Wrapf(err, "error parsing project context: %s", filepath.Join(dir, contextFile))
+	}
+	for _, f := range spec.Files {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		// the project can't load files outside of it (e.g. ~/.aws)
+		rel := filepath.Clean(filepath.FromSlash(f))
+		if outside(root, rel) {
+			return nil, errors.Errorf("project file outside of project directory %s: %s", root, f)
+		}
+		p.Context = append(p.Context, file.Scheme+":"+filepath.ToSlash(filepath.Join(root, rel)))
+	}
+	for _, u := range spec.URLs {
+		if u = strings.TrimSpace(u); u != "" {
+			p.URLs = append(p.URLs, url.Scheme+":"+u)
+		}
+	}
+	return p, nil
+}
+
+// outside returns true when the relative path points outside of root,
+// including through symbolic links. Only the part before glob pattern
+// (e.g. docs in docs/**/*.md) and fragment (e.g. #pages=1) is resolved.
+func outside(root, rel string) bool {
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || escapes(rel) {
+		return true
+	}
+
+	var elems []string
+	for _, e := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.ContainsAny(e, "*?[") {
+			break
+		}
+		if i := strings.Index(e, "#"); i >= 0 {
+			elems = append(elems, e[:i])
+			break
+		}
+		elems = append(elems, e)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return true
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(append([]string{root}, elems...)...))
+	if err != nil {
+		// missing files fail to load
+		return false
+	}
+	r, err := filepath.Rel(realRoot, path)
+	return err != nil || escapes(r)
+}
+
+func escapes(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,63 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "pkg", "api")
+	assert.NoError(t, os.MkdirAll(sub, 0o700))
+
+	p, err := Find(sub)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	dir := filepath.Join(root, Dir)
+	assert.NoError(t, os.Mkdir(dir, 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "instructions.md"), []byte("Use Go 1.21.\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "context.yaml"), []byte(`
+files:
+  - README.md
+  - docs/**/*.md
+  - ./docs/../api.md
+urls:
+  - https://go.dev/doc/effective_go
+`), 0o600))
+
+	p, err = Find(sub)
+	assert.NoError(t, err)
+	assert.Equal(t, root, p.Root)
+	assert.Equal(t, filepath.Join(dir, "instructions.md"), p.Instructions)
+	assert.Equal(t, []string{
+		"FILE:" + filepath.ToSlash(filepath.Join(root, "README.md")),
+		"FILE:" + filepath.ToSlash(filepath.Join(root, "docs/**/*.md")),
+		"FILE:" + filepath.ToSlash(filepath.Join(root, "api.md")),
+	}, p.Context)
+	assert.Equal(t, []string{"URL:https://go.dev/doc/effective_go"}, p.URLs)
+
+	for _, f := range []string{"/etc/hosts", "../secrets.txt", "docs/../../x", "docs/../../../etc/passwd"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "context.yaml"), []byte("files: ["+f+"]\n"), 0o600))
+		_, err = Find(root)
+		assert.ErrorContains(t, err, "project file outside of project directory", f)
+	}
+
+	// nor through symbolic links
+	secret := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, os.WriteFile(secret, []byte("key"), 0o600))
+	assert.NoError(t, os.Symlink(secret, filepath.Join(root, "creds")))
+	assert.NoError(t, os.Symlink(filepath.Dir(secret), filepath.Join(root, "home")))
+	for _, f := range []string{"creds", "home/*", "creds#pages=1"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "context.yaml"), []byte("files: [\""+f+"\"]\n"), 0o600))
+		_, err = Find(root)
+		assert.ErrorContains(t, err, "project file outside of project directory", f)
+	}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "context.yaml"), []byte("files: [\n"), 0o600))
+	_, err = Find(root)
+	assert.ErrorContains(t, err, "error parsing project context")
+}
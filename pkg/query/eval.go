@@ -0,0 +1,526 @@
+package query
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Value is the query value: nil, float64, string or bool.
+type Value interface{}
+
+var aggregates = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+// scope is the evaluation context of single row, or of a group of rows
+// when aggregating.
+type scope struct {
+	columns map[string]int
+	row     []Value
+	group   [][]Value
+	aliases map[string]Value // output values referenced in HAVING
+}
+
+func (s *scope) eval(e expr) (Value, error) {
+	switch e := e.(type) {
+	case *literal:
+		return e.v, nil
+	case *column:
+		i, ok := s.columns[strings.ToLower(e.name)]
+		if !ok {
+			if v, ok := s.aliases[strings.ToLower(e.name)]; ok {
+				return v, nil
+			}
+			return nil, errors.Errorf("unknown column '%s'", e.name)
+		}
+		if s.row == nil {
+			return nil, nil
+		}
+		return s.row[i], nil
+	case *unary:
+		v, err := s.eval(e.e)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		if e.op == "NOT" {
+			return !truthy(v), nil
+		}
+		f, ok := number(v)
+		if !ok {
+			return nil, errors.Errorf("invalid number '%v'", v)
+		}
+		return -f, nil
+	case *binary:
+		return s.binary(e)
+	case *between:
+		v, err := s.eval(e.e)
+		if err != nil {
+			return nil, err
+		}
+		lo, err := s.eval(e.lo)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := s.eval(e.hi)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil || lo == nil || hi == nil {
+			return nil, nil
+		}
+		return (compare(v, lo) >= 0 && compare(v, hi) <= 0) != e.not, nil
+	case *in:
+		v, err := s.eval(e.e)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		found := false
+		for _, x := range e.list {
+			w, err := s.eval(x)
+			if err != nil {
+				return nil, err
+			}
+			if w != nil && compare(v, w) == 0 {
+				found = true
+				break
+			}
+		}
+		return found != e.not, nil
+	case *like:
+		v, err := s.eval(e.e)
+		if err != nil {
+			return nil, err
+		}
+		p, err := s.eval(e.pattern)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil || p == nil {
+			return nil, nil
+		}
+		re, err := likePattern(format(p))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(format(v)) != e.not, nil
+	case *isNull:
+		v, err := s.eval(e.e)
+		if err != nil {
+			return nil, err
+		}
+		return (v == nil) != e.not, nil
+	case *call:
+		if aggregates[e.name] {
+			return s.aggregate(e)
+		}
+		return s.function(e)
+	}
+	return nil, errors.Errorf("unsupported expression %T", e)
+}
+
+func (s *scope) binary(e *binary) (Value, error) {
+	l, err := s.eval(e.l)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit logical operators
+	switch e.op {
+	case "AND":
+		if l != nil && !truthy(l) {
+			return false, nil
+		}
+	case "OR":
+		if l != nil && truthy(l) {
+			return true, nil
+		}
+	}
+
+	r, err := s.eval(e.r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "AND":
+		if r != nil && !truthy(r) {
+			return false, nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return true, nil
+	case "OR":
+		if r != nil && truthy(r) {
+			return true, nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return false, nil
+	}
+
+	if l == nil || r == nil {
+		return nil, nil
+	}
+
+	switch e.op {
+	case "=":
+		return compare(l, r) == 0, nil
+	case "!=":
+		return compare(l, r) != 0, nil
+	case "<":
+		return compare(l, r) < 0, nil
+	case "<=":
+		return compare(l, r) <= 0, nil
+	case ">":
+		return compare(l, r) > 0, nil
+	case ">=":
+		return compare(l, r) >= 0, nil
+	case "||":
+		return format(l) + format(r), nil
+	}
+
+	a, ok := number(l)
+	if !ok {
+		return nil, errors.Errorf("invalid number '%v' in %s", l, e.op)
+	}
+	b, ok := number(r)
+	if !ok {
+		return nil, errors.Errorf("invalid number '%v' in %s", r, e.op)
+	}
+
+	switch e.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, nil
+		}
+		return a / b, nil
+	case "%":
+		if b == 0 {
+			return nil, nil
+		}
+		return math.Mod(a, b), nil
+	}
+	return nil, errors.Errorf("unsupported operator %s", e.op)
+}
+
+func (s *scope) aggregate(e *call) (Value, error) {
+	if s.group == nil {
+		return nil, errors.Errorf("%s is not allowed here", e.name)
+	}
+	if !e.star && len(e.args) != 1 {
+		return nil, errors.Errorf("%s requires one argument", e.name)
+	}
+	if e.star && e.name != "COUNT" {
+		return nil, errors.Errorf("%s(*) is not supported", e.name)
+	}
+	if e.star {
+		return float64(len(s.group)), nil
+	}
+
+	var values []Value
+	seen := map[string]bool{}
+	for _, row := range s.group {
+		rs := &scope{columns: s.columns, row: row}
+		v, err := rs.eval(e.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		if e.distinct {
+			k := key(v)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		values = append(values, v)
+	}
+
+	switch e.name {
+	case "COUNT":
+		return float64(len(values)), nil
+	case "MIN", "MAX":
+		var m Value
+		for _, v := range values {
+			if m == nil || (e.name == "MIN" && compare(v, m) < 0) || (e.name == "MAX" && compare(v, m) > 0) {
+				m = v
+			}
+		}
+		return m, nil
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var sum float64
+	for _, v := range values {
+		f, ok := number(v)
+		if !ok {
+			return nil, errors.Errorf("invalid number '%v' in %s", v, e.name)
+		}
+		sum += f
+	}
+	if e.name == "AVG" {
+		return sum / float64(len(values)), nil
+	}
+	return sum, nil
+}
+
+func (s *scope) function(e *call) (Value, error) {
+	args := make([]Value, len(e.args))
+	for i, a := range e.args {
+		v, err := s.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	arity := func(lo, hi int) error {
+		if len(args) < lo || len(args) > hi {
+			return errors.Errorf("invalid number of arguments to %s", e.name)
+		}
+		return nil
+	}
+
+	switch e.name {
+	case "COALESCE":
+		for _, v := range args {
+			if v != nil {
+				return v, nil
+			}
+		}
+		return nil, nil
+	case "ROUND":
+		if err := arity(1, 2); err != nil {
+			return nil, err
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		f, ok := number(args[0])
+		if !ok {
+			return nil, errors.Errorf("invalid number '%v' in ROUND", args[0])
+		}
+		var n float64
+		if len(args) == 2 {
+			n, _ = number(args[1])
+		}
+		p := math.Pow(10, math.Trunc(n))
+		return math.Round(f*p) / p, nil
+	case "ABS":
+		if err := arity(1, 1); err != nil {
+			return nil, err
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		f, ok := number(args[0])
+		if !ok {
+			return nil, errors.Errorf("invalid number '%v' in ABS", args[0])
+		}
+		return math.Abs(f), nil
+	case "LOWER", "UPPER", "LENGTH", "TRIM":
+		if err := arity(1, 1); err != nil {
+			return nil, err
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		v := format(args[0])
+		switch e.name {
+		case "LOWER":
+			return strings.ToLower(v), nil
+		case "UPPER":
+			return strings.ToUpper(v), nil
+		case "TRIM":
+			return strings.TrimSpace(v), nil
+		}
+		return float64(len([]rune(v))), nil
+	case "SUBSTR", "SUBSTRING":
+		if err := arity(2, 3); err != nil {
+			return nil, err
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		rs := []rune(format(args[0]))
+		start, _ := number(args[1])
+		from := int(start) - 1 // 1-based
+		if from < 0 {
+			from = 0
+		}
+		if from > len(rs) {
+			from = len(rs)
+		}
+		to := len(rs)
+		if len(args) == 3 {
+			n, _ := number(args[2])
+			if from+int(n) < to {
+				to = from + int(n)
+			}
+		}
+		if to < from {
+			to = from
+		}
+		return string(rs[from:to]), nil
+	}
+	return nil, errors.Errorf("unknown function %s", e.name)
+}
+
+// hasAggregate reports whether the expression contains aggregate function.
+func hasAggregate(e expr) bool {
+	switch e := e.(type) {
+	case *call:
+		if aggregates[e.name] {
+			return true
+		}
+		for _, a := range e.args {
+			if hasAggregate(a) {
+				return true
+			}
+		}
+	case *unary:
+		return hasAggregate(e.e)
+	case *binary:
+		return hasAggregate(e.l) || hasAggregate(e.r)
+	case *between:
+		return hasAggregate(e.e) || hasAggregate(e.lo) || hasAggregate(e.hi)
+	case *in:
+		if hasAggregate(e.e) {
+			return true
+		}
+		for _, x := range e.list {
+			if hasAggregate(x) {
+				return true
+			}
+		}
+	case *like:
+		return hasAggregate(e.e) || hasAggregate(e.pattern)
+	case *isNull:
+		return hasAggregate(e.e)
+	}
+	return false
+}
+
+func truthy(v Value) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return err == nil && f != 0
+	}
+	return false
+}
+
+func number(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compare compares the values numerically when both are numbers, and as
+// (case insensitive) strings otherwise. Nil sorts first.
+func compare(a, b Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(format(a)), strings.ToLower(format(b)))
+}
+
+// format returns the string representation of the value.
+func format(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case string:
+		return v
+	}
+	return ""
+}
+
+// key returns the value identity for grouping and DISTINCT.
+func key(v Value) string {
+	if v == nil {
+		return "\x00"
+	}
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strings.ToLower(format(v))
+}
+
+func likePattern(p string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for _, r := range p {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid LIKE pattern: %s", p)
+	}
+	return re, nil
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokQuoted // quoted identifier
+	tokString
+	tokNumber
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// is reports whether the token is the keyword or symbol (case insensitive).
+func (t token) is(s string) bool {
+	return (t.kind == tokIdent || t.kind == tokSymbol) && strings.EqualFold(t.text, s)
+}
+
+var symbols = []string{"<=", ">=", "<>", "!=", "||", "=", "<", ">", "(", ")", ",", "*", "+", "-", "/", "%", ".", ";"}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(rs) && rs[i+1] == '-':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			end := r
+			if r == '[' {
+				end = ']'
+			}
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs); j++ {
+				if rs[j] == end {
+					// doubled quote is escaped quote
+					if j+1 < len(rs) && rs[j+1] == end && end != ']' {
+						sb.WriteRune(end)
+						j++
+						continue
+					}
+					break
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, errors.Errorf("unterminated quote at position %d", i)
+			}
+			kind := tokQuoted
+			if r == '\'' {
+				kind = tokString
+			}
+			tokens = append(tokens, token{kind: kind, text: sb.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+				((rs[j] == '-' || rs[j] == '+') && (rs[j-1] == 'e' || rs[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: string(rs[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(rs[i:j])})
+			i = j
+		default:
+			matched := false
+			for _, sym := range symbols {
+				if strings.HasPrefix(string(rs[i:]), sym) {
+					tokens = append(tokens, token{kind: tokSymbol, text: sym})
+					i += len([]rune(sym))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, errors.Errorf("unexpected character '%c' at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
@@ -0,0 +1,506 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	expr interface{}
+
+	column struct {
+		name string
+	}
+
+	literal struct {
+		v Value
+	}
+
+	binary struct {
+		op   string
+		l, r expr
+	}
+
+	unary struct {
+		op string
+		e  expr
+	}
+
+	call struct {
+		name     string
+		args     []expr
+		star     bool
+		distinct bool
+	}
+
+	between struct {
+		e, lo, hi expr
+		not       bool
+	}
+
+	in struct {
+		e    expr
+		list []expr
+		not  bool
+	}
+
+	like struct {
+		e, pattern expr
+		not        bool
+	}
+
+	isNull struct {
+		e   expr
+		not bool
+	}
+
+	item struct {
+		e     expr
+		alias string
+		star  bool
+	}
+
+	order struct {
+		e    expr
+		desc bool
+	}
+
+	statement struct {
+		distinct bool
+		items    []*item
+		from     string
+		where    expr
+		groupBy  []expr
+		having   expr
+		orderBy  []*order
+		limit    int
+	}
+)
+
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true, "HAVING": true,
+	"ORDER": true, "LIMIT": true, "AND": true, "OR": true, "NOT": true, "AS": true, "ASC": true,
+	"DESC": true, "BETWEEN": true, "IN": true, "LIKE": true, "IS": true, "NULL": true, "DISTINCT": true,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(sql string) (*statement, error) {
+	tokens, err := lex(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	st, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	p.accept(";")
+	if p.peek().kind != tokEOF {
+		return nil, errors.Errorf("unexpected '%s'", p.peek().text)
+	}
+	return st, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(s string) bool {
+	if p.peek().is(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return errors.Errorf("expected %s, got '%s'", s, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) statement() (*statement, error) {
+	st := &statement{limit: -1}
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	st.distinct = p.accept("DISTINCT")
+
+	for {
+		it := &item{}
+		if p.accept("*") {
+			it.star = true
+		} else {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			it.e = e
+			if p.accept("AS") {
+				t := p.next()
+				if t.kind != tokIdent && t.kind != tokQuoted && t.kind != tokString {
+					return nil, errors.Errorf("invalid alias '%s'", t.text)
+				}
+				it.alias = t.text
+			} else if t := p.peek(); t.kind == tokQuoted || (t.kind == tokIdent && !reserved[strings.ToUpper(t.text)]) {
+				it.alias = p.next().text
+			}
+		}
+		st.items = append(st.items, it)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	if t.kind != tokIdent && t.kind != tokQuoted {
+		return nil, errors.Errorf("invalid table name '%s'", t.text)
+	}
+	st.from = t.text
+	// optional table alias
+	if p.accept("AS") {
+		p.next()
+	} else if t := p.peek(); t.kind == tokIdent && !reserved[strings.ToUpper(t.text)] {
+		p.next()
+	}
+
+	var err error
+	if p.accept("WHERE") {
+		if st.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("GROUP") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		if st.groupBy, err = p.exprList(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("HAVING") {
+		if st.having, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			o := &order{e: e}
+			if p.accept("DESC") {
+				o.desc = true
+			} else {
+				p.accept("ASC")
+			}
+			st.orderBy = append(st.orderBy, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("LIMIT") {
+		t := p.next()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != tokNumber || err != nil || n < 0 {
+			return nil, errors.Errorf("invalid limit '%s'", t.text)
+		}
+		st.limit = n
+	}
+
+	return st, nil
+}
+
+func (p *parser) exprList() ([]expr, error) {
+	var list []expr
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.accept(",") {
+			return list, nil
+		}
+	}
+}
+
+func (p *parser) expr() (expr, error) {
+	return p.or()
+}
+
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "OR", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) and() (expr, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: "AND", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) not() (expr, error) {
+	if p.accept("NOT") {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "NOT", e: e}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expr, error) {
+	l, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"=", "!=", "<>", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			r, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			if op == "<>" {
+				op = "!="
+			}
+			return &binary{op: op, l: l, r: r}, nil
+		}
+	}
+
+	if p.accept("IS") {
+		not := p.accept("NOT")
+		if err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return &isNull{e: l, not: not}, nil
+	}
+
+	not := p.accept("NOT")
+	switch {
+	case p.accept("BETWEEN"):
+		lo, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return &between{e: l, lo: lo, hi: hi, not: not}, nil
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		list, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &in{e: l, list: list, not: not}, nil
+	case p.accept("LIKE"):
+		r, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return &like{e: l, pattern: r, not: not}, nil
+	}
+	if not {
+		return nil, errors.Errorf("unexpected '%s' after NOT", p.peek().text)
+	}
+
+	return l, nil
+}
+
+func (p *parser) additive() (expr, error) {
+	l, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		case p.accept("||"):
+			op = "||"
+		default:
+			return l, nil
+		}
+		r, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) multiplicative() (expr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		case p.accept("%"):
+			op = "%"
+		default:
+			return l, nil
+		}
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.accept("-") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "-", e: e}, nil
+	}
+	p.accept("+")
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid number '%s'", t.text)
+		}
+		return &literal{v: f}, nil
+	case tokString:
+		return &literal{v: t.text}, nil
+	case tokQuoted:
+		return p.qualified(t.text), nil
+	case tokIdent:
+		upper := strings.ToUpper(t.text)
+		switch upper {
+		case "NULL":
+			return &literal{v: nil}, nil
+		case "TRUE":
+			return &literal{v: true}, nil
+		case "FALSE":
+			return &literal{v: false}, nil
+		}
+		if p.accept("(") {
+			return p.call(upper)
+		}
+		if reserved[upper] {
+			return nil, errors.Errorf("unexpected '%s'", t.text)
+		}
+		return p.qualified(t.text), nil
+	case tokSymbol:
+		if t.text == "(" {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case tokEOF:
+		return nil, errors.New("unexpected end of query")
+	}
+	return nil, errors.Errorf("unexpected '%s'", t.text)
+}
+
+// qualified returns column reference, dropping the table qualifier
+// (e.g. t.col) as queries are run over single table.
+func (p *parser) qualified(name string) expr {
+	if p.accept(".") {
+		t := p.next()
+		return &column{name: t.text}
+	}
+	return &column{name: name}
+}
+
+func (p *parser) call(name string) (expr, error) {
+	c := &call{name: name}
+	if p.accept(")") {
+		return c, nil
+	}
+	if p.accept("*") {
+		c.star = true
+	} else {
+		c.distinct = p.accept("DISTINCT")
+		args, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		c.args = args
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
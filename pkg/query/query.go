@@ -0,0 +1,475 @@
+// Package query runs SQL SELECT queries over tables loaded in memory. It
+// supports a subset of SQL: single table with WHERE, GROUP BY, HAVING,
+// ORDER BY, LIMIT, DISTINCT, aggregates (COUNT, SUM, AVG, MIN, MAX) and
+// common scalar functions.
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/pkg/errors"
+)
+
+// Result is the query result.
+type Result struct {
+	Columns []string
+	Rows    [][]Value
+}
+
+var (
+	nonWord   = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+	fencedSQL = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*\\n(.*?)```")
+)
+
+// TableName returns the name of the table used in queries: file name
+// without extension with non-word characters replaced by underscore.
+func TableName(t *table.Table) string {
+	name := strings.TrimSuffix(t.Name, filepath.Ext(t.Name))
+	return strings.Trim(nonWord.ReplaceAllString(name, "_"), "_")
+}
+
+// Extract returns the SQL query from the model response which may wrap
+// it in code fence.
+func Extract(s string) string {
+	if m := fencedSQL.FindStringSubmatch(s); m != nil {
+		s = m[1]
+	}
+	return strings.TrimSuffix(strings.TrimSpace(s), ";")
+}
+
+// Run parses and executes the SQL query over the tables.
+func Run(sql string, tables []*table.Table) (*Result, error) {
+	st, err := parse(sql)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing query")
+	}
+
+	t := find(st.from, tables)
+	if t == nil {
+		return nil, errors.Errorf("unknown table '%s'", st.from)
+	}
+
+	res, err := execute(st, t)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing query")
+	}
+	return res, nil
+}
+
+func find(name string, tables []*table.Table) *table.Table {
+	for _, t := range tables {
+		if strings.EqualFold(name, t.Name) || strings.EqualFold(name, TableName(t)) ||
+			strings.EqualFold(name, strings.TrimSuffix(t.Name, filepath.Ext(t.Name))) {
+			return t
+		}
+	}
+	return nil
+}
+
+type output struct {
+	values []Value
+	src    *scope
+}
+
+func execute(st *statement, t *table.Table) (*Result, error) {
+	columns := map[string]int{}
+	for i, c := range t.Columns {
+		columns[strings.ToLower(c.Name)] = i
+	}
+	// allow snake case names of columns with spaces or dashes
+	for i, c := range t.Columns {
+		alt := strings.ToLower(strings.Trim(nonWord.ReplaceAllString(c.Name, "_"), "_"))
+		if _, ok := columns[alt]; !ok {
+			columns[alt] = i
+		}
+	}
+
+	var rows [][]Value
+	for _, r := range t.Rows {
+		row := convert(t, r)
+		if st.where != nil {
+			v, err := (&scope{columns: columns, row: row}).eval(st.where)
+			if err != nil {
+				return nil, err
+			}
+			if !truthy(v) {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	res := &Result{}
+	for _, it := range st.items {
+		if it.star {
+			for _, c := range t.Columns {
+				res.Columns = append(res.Columns, c.Name)
+			}
+			continue
+		}
+		res.Columns = append(res.Columns, name(it))
+	}
+
+	var scopes []*scope
+	if grouped(st) {
+		groups, err := group(st, columns, rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			s := &scope{columns: columns, group: g}
+			if len(g) > 0 {
+				s.row = g[0]
+			}
+			scopes = append(scopes, s)
+		}
+	} else {
+		for _, r := range rows {
+			scopes = append(scopes, &scope{columns: columns, row: r})
+		}
+	}
+
+	var out []*output
+	for _, s := range scopes {
+		o := &output{src: s}
+		for _, it := range st.items {
+			if it.star {
+				if s.row == nil {
+					o.values = append(o.values, make([]Value, len(t.Columns))...)
+				} else {
+					o.values = append(o.values, s.row...)
+				}
+				continue
+			}
+			v, err := s.eval(it.e)
+			if err != nil {
+				return nil, err
+			}
+			o.values = append(o.values, v)
+		}
+		if st.having != nil {
+			s.aliases = map[string]Value{}
+			for i, n := range res.Columns {
+				s.aliases[strings.ToLower(n)] = o.values[i]
+			}
+			v, err := s.eval(st.having)
+			if err != nil {
+				return nil, err
+			}
+			if !truthy(v) {
+				continue
+			}
+		}
+		out = append(out, o)
+	}
+
+	if err := sortOutput(st, res.Columns, out); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, o := range out {
+		if st.distinct {
+			keys := make([]string, len(o.values))
+			for i, v := range o.values {
+				keys[i] = key(v)
+			}
+			k := strings.Join(keys, "\x01")
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		if st.limit >= 0 && len(res.Rows) >= st.limit {
+			break
+		}
+		res.Rows = append(res.Rows, o.values)
+	}
+
+	return res, nil
+}
+
+// convert returns the typed row values. Empty values are nil.
+func convert(t *table.Table, r []string) []Value {
+	row := make([]Value, len(r))
+	for i, v := range r {
+		if v == "" {
+			continue
+		}
+		row[i] = v
+		switch t.Columns[i].Type {
+		case table.Integer, table.Float:
+			if f, ok := t.Columns[i].Float(v); ok {
+				row[i] = f
+			}
+		}
+	}
+	return row
+}
+
+func grouped(st *statement) bool {
+	if len(st.groupBy) > 0 || st.having != nil {
+		return true
+	}
+	for _, it := range st.items {
+		if !it.star && hasAggregate(it.e) {
+			return true
+		}
+	}
+	return false
+}
+
+// group splits rows by the GROUP BY values keeping the order of the first
+// occurrence. Without GROUP BY all rows form single group.
+func group(st *statement, columns map[string]int, rows [][]Value) ([][][]Value, error) {
+	if len(st.groupBy) == 0 {
+		return [][][]Value{rows}, nil
+	}
+
+	exprs := make([]expr, len(st.groupBy))
+	for i, e := range st.groupBy {
+		ge, err := groupExpr(e, st.items, columns)
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = ge
+	}
+
+	var groups [][][]Value
+	index := map[string]int{}
+	for _, r := range rows {
+		s := &scope{columns: columns, row: r}
+		keys := make([]string, len(exprs))
+		for i, e := range exprs {
+			v, err := s.eval(e)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = key(v)
+		}
+		k := strings.Join(keys, "\x01")
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups, nil
+}
+
+// groupExpr returns the expression of GROUP BY term, which can refer to
+// the select item by position or by alias (unless it's a source column).
+func groupExpr(e expr, items []*item, columns map[string]int) (expr, error) {
+	var it *item
+	switch e := e.(type) {
+	case *literal:
+		if f, ok := e.v.(float64); ok {
+			i := int(f)
+			if i < 1 || i > len(items) || items[i-1].star {
+				return nil, errors.Errorf("GROUP BY position %d out of range", i)
+			}
+			it = items[i-1]
+		}
+	case *column:
+		if _, ok := columns[strings.ToLower(e.name)]; ok {
+			return e, nil
+		}
+		for _, i := range items {
+			if !i.star && strings.EqualFold(i.alias, e.name) {
+				it = i
+				break
+			}
+		}
+	}
+	if it == nil {
+		return e, nil
+	}
+	if hasAggregate(it.e) {
+		return nil, errors.Errorf("aggregate function in GROUP BY: %s", name(it))
+	}
+	return it.e, nil
+}
+
+// sortOutput sorts rows by ORDER BY terms which can refer to the output
+// column (by alias or position) or to any expression over the source.
+func sortOutput(st *statement, names []string, out []*output) error {
+	if len(st.orderBy) == 0 {
+		return nil
+	}
+
+	keys := make([][]Value, len(out))
+	for i, o := range out {
+		keys[i] = make([]Value, len(st.orderBy))
+		for j, ob := range st.orderBy {
+			v, err := orderValue(ob.e, names, o)
+			if err != nil {
+				return err
+			}
+			keys[i][j] = v
+		}
+	}
+
+	idx := make([]int, len(out))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		for j, ob := range st.orderBy {
+			c := compare(keys[idx[a]][j], keys[idx[b]][j])
+			if c == 0 {
+				continue
+			}
+			if ob.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	sorted := make([]*output, len(out))
+	for i, j := range idx {
+		sorted[i] = out[j]
+	}
+	copy(out, sorted)
+	return nil
+}
+
+func orderValue(e expr, names []string, o *output) (Value, error) {
+	switch e := e.(type) {
+	case *literal:
+		if f, ok := e.v.(float64); ok {
+			i := int(f)
+			if i < 1 || i > len(o.values) {
+				return nil, errors.Errorf("ORDER BY position %d out of range", i)
+			}
+			return o.values[i-1], nil
+		}
+	case *column:
+		for i, n := range names {
+			if strings.EqualFold(n, e.name) {
+				return o.values[i], nil
+			}
+		}
+	}
+	return o.src.eval(e)
+}
+
+// name returns the output column name of the select item.
+func name(it *item) string {
+	if it.alias != "" {
+		return it.alias
+	}
+	return describe(it.e)
+}
+
+// describe returns the SQL text of the expression.
+func describe(e expr) string {
+	switch e := e.(type) {
+	case *literal:
+		if s, ok := e.v.(string); ok {
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		if e.v == nil {
+			return "NULL"
+		}
+		return format(e.v)
+	case *column:
+		return e.name
+	case *unary:
+		if e.op == "NOT" {
+			return "NOT " + describe(e.e)
+		}
+		return e.op + describe(e.e)
+	case *binary:
+		return describe(e.l) + " " + e.op + " " + describe(e.r)
+	case *call:
+		if e.star {
+			return e.name + "(*)"
+		}
+		args := make([]string, len(e.args))
+		for i, a := range e.args {
+			args[i] = describe(a)
+		}
+		prefix := ""
+		if e.distinct {
+			prefix = "DISTINCT "
+		}
+		return e.name + "(" + prefix + strings.Join(args, ", ") + ")"
+	case *between:
+		return describe(e.e) + not(e.not) + " BETWEEN " + describe(e.lo) + " AND " + describe(e.hi)
+	case *in:
+		list := make([]string, len(e.list))
+		for i, x := range e.list {
+			list[i] = describe(x)
+		}
+		return describe(e.e) + not(e.not) + " IN (" + strings.Join(list, ", ") + ")"
+	case *like:
+		return describe(e.e) + not(e.not) + " LIKE " + describe(e.pattern)
+	case *isNull:
+		if e.not {
+			return describe(e.e) + " IS NOT NULL"
+		}
+		return describe(e.e) + " IS NULL"
+	}
+	return fmt.Sprintf("%v", e)
+}
+
+func not(b bool) string {
+	if b {
+		return " NOT"
+	}
+	return ""
+}
+
+// Strings returns the row values formatted as strings.
+func (r *Result) Strings(row []Value) []string {
+	s := make([]string, len(row))
+	for i, v := range row {
+		s[i] = format(v)
+	}
+	return s
+}
+
+// Markdown returns the result as Markdown table. When the result has more
+// than limit rows (and limit is positive), only the first limit rows are included.
+func (r *Result) Markdown(limit int) string {
+	var sb strings.Builder
+	cell := func(s string) string {
+		return strings.ReplaceAll(s, "|", "\\|")
+	}
+
+	sb.WriteString("|")
+	for _, c := range r.Columns {
+		sb.WriteString(" " + cell(c) + " |")
+	}
+	sb.WriteString("\n|")
+	for range r.Columns {
+		sb.WriteString(" --- |")
+	}
+	sb.WriteString("\n")
+
+	rows := r.Rows
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		sb.WriteString("|")
+		for _, v := range r.Strings(row) {
+			sb.WriteString(" " + cell(v) + " |")
+		}
+		sb.WriteString("\n")
+	}
+	if len(rows) < len(r.Rows) {
+		fmt.Fprintf(&sb, "\n(%d of %d rows)\n", len(rows), len(r.Rows))
+	}
+
+	return sb.String()
+}
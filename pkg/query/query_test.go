@@ -0,0 +1,131 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mchmarny/aictl/pkg/content/table"
+	"github.com/stretchr/testify/assert"
+)
+
+const data = `city,state,population,area,founded
+Portland,OR,650000,145.1,1845-02-08
+Eugene,OR,177000,44.2,1862-10-17
+Seattle,WA,737000,142.5,1851-11-13
+Spokane,WA,229000,69.5,1881-11-29
+Boise,ID,235000,84.0,
+`
+
+func testTables(t *testing.T) []*table.Table {
+	tb, err := table.Read("us cities.csv", strings.NewReader(data), ',')
+	assert.NoError(t, err)
+	return []*table.Table{tb}
+}
+
+func TestRun(t *testing.T) {
+	tables := testTables(t)
+
+	tests := []struct {
+		name    string
+		sql     string
+		columns []string
+		rows    [][]string
+	}{
+		{
+			name:    "filter and order",
+			sql:     "SELECT city, population FROM us_cities WHERE state = 'OR' ORDER BY population DESC",
+			columns: []string{"city", "population"},
+			rows:    [][]string{{"Portland", "650000"}, {"Eugene", "177000"}},
+		},
+		{
+			name:    "group by",
+			sql:     `SELECT state, COUNT(*) AS n, SUM(population) total FROM "us cities.csv" GROUP BY state HAVING n > 1 ORDER BY total`,
+			columns: []string{"state", "n", "total"},
+			rows:    [][]string{{"OR", "2", "827000"}, {"WA", "2", "966000"}},
+		},
+		{
+			name:    "group by alias",
+			sql:     "SELECT substr(founded, 1, 3) AS decade, AVG(area) AS a FROM us_cities WHERE founded IS NOT NULL GROUP BY decade ORDER BY decade LIMIT 2",
+			columns: []string{"decade", "a"},
+			rows:    [][]string{{"184", "145.1"}, {"185", "142.5"}},
+		},
+		{
+			name:    "group by position",
+			sql:     "SELECT lower(state), MAX(population) FROM us_cities GROUP BY 1 ORDER BY 2 DESC LIMIT 2",
+			columns: []string{"LOWER(state)", "MAX(population)"},
+			rows:    [][]string{{"wa", "737000"}, {"or", "650000"}},
+		},
+		{
+			name:    "aggregate",
+			sql:     "select round(avg(area), 1), max(city), count(founded) from us_cities",
+			columns: []string{"ROUND(AVG(area), 1)", "MAX(city)", "COUNT(founded)"},
+			rows:    [][]string{{"97.1", "Spokane", "4"}},
+		},
+		{
+			name:    "expressions",
+			sql:     "SELECT DISTINCT state, substr(founded, 1, 4) AS year FROM us_cities WHERE founded BETWEEN '1850' AND '1890' AND city LIKE 's%' ORDER BY 2 LIMIT 1",
+			columns: []string{"state", "year"},
+			rows:    [][]string{{"WA", "1851"}},
+		},
+		{
+			name:    "null and in",
+			sql:     "SELECT * FROM us_cities WHERE founded IS NULL OR state NOT IN ('OR', 'WA');",
+			columns: []string{"city", "state", "population", "area", "founded"},
+			rows:    [][]string{{"Boise", "ID", "235000", "84", ""}},
+		},
+		{
+			name:    "arithmetic",
+			sql:     "SELECT city, population / area AS density FROM us_cities ORDER BY density DESC LIMIT 1",
+			columns: []string{"city", "density"},
+			rows:    [][]string{{"Seattle", "5171.929825"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Run(tt.sql, tables)
+			assert.NoError(t, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.columns, res.Columns)
+			var rows [][]string
+			for _, r := range res.Rows {
+				rows = append(rows, res.Strings(r))
+			}
+			assert.Equal(t, tt.rows, rows)
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tables := testTables(t)
+
+	for _, sql := range []string{
+		"",
+		"DELETE FROM us_cities",
+		"SELECT city FROM missing",
+		"SELECT missing FROM us_cities",
+		"SELECT city FROM us_cities WHERE",
+		"SELECT city FROM us_cities WHERE SUM(area) > 1",
+		"SELECT foo(city) FROM us_cities",
+		"SELECT COUNT(*) FROM us_cities GROUP BY nosuch",
+		"SELECT state, COUNT(*) AS n FROM us_cities GROUP BY n",
+		"SELECT state FROM us_cities GROUP BY 3",
+		"SELECT 'unterminated FROM us_cities",
+	} {
+		_, err := Run(sql, tables)
+		assert.Error(t, err, sql)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	assert.Equal(t, "SELECT 1 FROM t", Extract("```sql\nSELECT 1 FROM t;\n```"))
+	assert.Equal(t, "SELECT 1 FROM t", Extract(" SELECT 1 FROM t; "))
+}
+
+func TestMarkdown(t *testing.T) {
+	res := &Result{Columns: []string{"a", "b"}, Rows: [][]Value{{1.0, "x|y"}, {2.5, nil}}}
+	assert.Equal(t, "| a | b |\n| --- | --- |\n| 1 | x\\|y |\n| 2.5 |  |\n", res.Markdown(0))
+	assert.Contains(t, res.Markdown(1), "(1 of 2 rows)")
+}
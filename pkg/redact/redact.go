@@ -0,0 +1,341 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mchmarny/aictl/pkg/content"
+	"github.com/pkg/errors"
+)
+
+const (
+	// minimum length and Shannon entropy (bits per character) of strings
+	// detected as secrets, hex strings (e.g. commit hashes) stay below it
+	entropyMinLen = 24
+	entropyMin    = 4.0
+)
+
+// Rule is custom redaction rule, values matching the regular expression
+// Pattern are redacted. When the pattern has groups, only the value of the
+// first group is redacted (e.g. `password=(\S+)`).
+type Rule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+type detector struct {
+	name  string // used in placeholders and to disable the detector
+	label string // used in summary
+	re    *regexp.Regexp
+	// valid filters the matched values, when set
+	valid func(string) bool
+}
+
+var placeholderExp = regexp.MustCompile(`\[REDACTED_[A-Z0-9_]+_\d+\]`)
+
+// detectors are the built-in detectors, in order of precedence.
+var detectors = []*detector{
+	{
+		name:  "private-key",
+		label: "private key",
+		re:    regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END[A-Z ]*PRIVATE KEY-----|\z)`),
+	},
+	{
+		name:  "jwt",
+		label: "JWT",
+		re:    regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	},
+	{
+		name:  "aws-access-key",
+		label: "AWS access key",
+		re:    regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`),
+	},
+	{
+		name:  "aws-secret-key",
+		label: "AWS secret key",
+		re:    regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`),
+	},
+	{
+		name:  "gcp-api-key",
+		label: "Google API key",
+		re:    regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+	},
+	{
+		name:  "azure-key",
+		label: "Azure storage key",
+		re:    regexp.MustCompile(`AccountKey=([A-Za-z0-9+/]{86}==)`),
+	},
+	{
+		name:  "github-token",
+		label: "GitHub token",
+		re:    regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`),
+	},
+	{
+		name:  "slack-token",
+		label: "Slack token",
+		re:    regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	},
+	{
+		name:  "stripe-key",
+		label: "Stripe key",
+		re:    regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{24,}`),
+	},
+	{
+		name:  "email",
+		label: "email address",
+		re:    regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	},
+	{
+		name:  "ip",
+		label: "IP address",
+		re:    regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+		valid: func(v string) bool {
+			ip := net.ParseIP(v)
+			return ip != nil && !ip.IsLoopback() && !ip.IsUnspecified()
+		},
+	},
+	{
+		name:  "entropy",
+		label: "high-entropy string",
+		re:    regexp.MustCompile(`[A-Za-z0-9+_=-]{24,}`),
+		valid: secret,
+	},
+}
+
+// Names returns the names of the built-in detectors.
+func Names() []string {
+	names := make([]string, 0, len(detectors))
+	for _, d := range detectors {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// Redactor replaces secrets and personal information (e.g. API keys or
+// email addresses) with placeholders. The placeholders are stable, the
+// same value is always replaced with the same placeholder (e.g.
+// [REDACTED_EMAIL_1]), so the model can still tell the values apart.
+type Redactor struct {
+	detectors []*detector
+
+	mu        sync.Mutex
+	values    map[string]string // placeholders by detector and value
+	originals map[string]string // values by placeholder
+	counts    map[string]int    // distinct values by detector
+}
+
+// New returns redactor using the built-in detectors, except the disabled
+// ones (see Names), followed by the custom rules.
+func New(rules []*Rule, disable []string) (*Redactor, error) {
+	off := map[string]bool{}
+	for _, name := range disable {
+		if !contains(Names(), name) {
+			return nil, errors.Errorf("unknown redaction detector: %s, expected one of %s", name, strings.Join(Names(), ", "))
+		}
+		off[name] = true
+	}
+
+	r := &Redactor{
+		values:    map[string]string{},
+		originals: map[string]string{},
+		counts:    map[string]int{},
+	}
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, errors.New("redaction rule requires name and pattern")
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern of redaction rule: %s", rule.Name)
+		}
+		r.detectors = append(r.detectors, &detector{name: rule.Name, label: rule.Name, re: re})
+	}
+	for _, d := range detectors {
+		if !off[d.name] {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+	return r, nil
+}
+
+// Summary is the number of redacted values by their kind.
+type Summary map[string]int
+
+// String returns the kinds of redacted values with their numbers (e.g.
+// "AWS access key (1), email address (2)").
+func (s Summary) String() string {
+	labels := make([]string, 0, len(s))
+	for l := range s {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+
+	list := make([]string, 0, len(labels))
+	for _, l := range labels {
+		list = append(list, fmt.Sprintf("%s (%d)", l, s[l]))
+	}
+	return strings.Join(list, ", ")
+}
+
+func (s Summary) add(o Summary) {
+	for l, n := range o {
+		s[l] += n
+	}
+}
+
+type span struct {
+	start, end int
+	d          *detector
+}
+
+// Text returns txt with the detected values replaced by placeholders,
+// and the summary of what was redacted. Values matched by more than one
+// detector are redacted by the first one.
+func (r *Redactor) Text(txt string) (string, Summary) {
+	var spans []span
+	for _, d := range r.detectors {
+		for _, m := range d.re.FindAllStringSubmatchIndex(txt, -1) {
+			start, end := m[0], m[1]
+			if len(m) > 3 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if start == end || (d.valid != nil && !d.valid(txt[start:end])) || overlaps(spans, start, end) {
+				continue
+			}
+			spans = append(spans, span{start, end, d})
+		}
+	}
+
+	s := Summary{}
+	if len(spans) == 0 {
+		return txt, s
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var sb strings.Builder
+	last := 0
+	for _, sp := range spans {
+		sb.WriteString(txt[last:sp.start])
+		sb.WriteString(r.placeholder(sp.d, txt[sp.start:sp.end]))
+		s[sp.d.label]++
+		last = sp.end
+	}
+	sb.WriteString(txt[last:])
+	return sb.String(), s
+}
+
+// Parts returns the parts with the text ones redacted (see Text), and the
+// summary of what was redacted.
+func (r *Redactor) Parts(parts []content.Part) ([]content.Part, Summary) {
+	s := Summary{}
+	list := make([]content.Part, 0, len(parts))
+	for _, p := range parts {
+		if t, ok := p.(content.Text); ok {
+			txt, ts := r.Text(string(t))
+			s.add(ts)
+			p = content.Text(txt)
+		}
+		list = append(list, p)
+	}
+	return list, s
+}
+
+// placeholder returns the placeholder of value detected by d.
+func (r *Redactor) placeholder(d *detector, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := d.name + "\x00" + value
+	if p, ok := r.values[key]; ok {
+		return p
+	}
+	r.counts[d.name]++
+	p := fmt.Sprintf("[REDACTED_%s_%d]", placeholderName(d.name), r.counts[d.name])
+	r.values[key] = p
+	r.originals[p] = value
+	return p
+}
+
+// Restore returns txt with the placeholders replaced by the original
+// values, e.g. to run locally query written by the model.
+func (r *Redactor) Restore(txt string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return placeholderExp.ReplaceAllStringFunc(txt, func(p string) string {
+		if v, ok := r.originals[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+// placeholderName returns name in upper case with other characters than
+// letters and digits replaced by underscores.
+func placeholderName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+func overlaps(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// secret returns true when v looks like randomly generated secret: long
+// string of mixed case letters and digits with high entropy.
+func secret(v string) bool {
+	if len(v) < entropyMinLen {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, r := range v {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit && entropy(v) > entropyMin
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	freq := map[rune]float64{}
+	for _, r := range s {
+		freq[r]++
+	}
+	n := float64(len(s))
+	e := 0.0
+	for _, f := range freq {
+		p := f / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}